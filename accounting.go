@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Accounting selects which kernel counters the read/write columns show.
+type Accounting int
+
+const (
+	// AccountingBlock counts bytes that reached the block layer (disk I/O).
+	AccountingBlock Accounting = iota
+	// AccountingVFS counts bytes passed through read/write syscalls,
+	// including those served from the page cache.
+	AccountingVFS
+)
+
+var currentAccounting Accounting
+
+func parseAccounting(s string) (Accounting, error) {
+	switch s {
+	case "block":
+		return AccountingBlock, nil
+	case "vfs":
+		return AccountingVFS, nil
+	}
+	return AccountingBlock, fmt.Errorf("unknown accounting %q (want block or vfs)", s)
+}
+
+func (a Accounting) Label() string {
+	if a == AccountingVFS {
+		return "VFS I/O, includes cache hits"
+	}
+	return "block I/O, excludes cache hits"
+}
+
+// accountingNames lists the accounting sources in -accounting flag order.
+var accountingNames = []string{"block", "vfs"}
+
+func readCounters(a Accounting, pid int32) error {
+	if a == AccountingVFS {
+		_, _, err := vfsCounters(pid)
+		return err
+	}
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return err
+	}
+	_, err = p.IOCounters()
+	return err
+}
+
+// listAccounting reports, for each -accounting choice, whether it works on
+// this platform and whether other users' processes can be read with the
+// current privileges.
+func listAccounting(w io.Writer) {
+	for _, name := range accountingNames {
+		a, _ := parseAccounting(name)
+		status := "usable for all processes"
+		if err := readCounters(a, int32(os.Getpid())); err != nil {
+			status = "unavailable: " + err.Error()
+		} else if err := readCounters(a, 1); err != nil {
+			status = "usable for your own processes only; others need root or CAP_SYS_PTRACE"
+		}
+		fmt.Fprintf(w, "%-6s %s\n       %s\n", name, a.Label(), status)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+)
+
+// baselineEntry is a process's cumulative I/O when the baseline was taken.
+// CreateTime tells a reused PID apart from the original process.
+type baselineEntry struct {
+	Name       string  `json:"name"`
+	CreateTime int64   `json:"create_time"`
+	Read       float64 `json:"read_bytes"`
+	Write      float64 `json:"write_bytes"`
+}
+
+type baseline struct {
+	Name    string                  `json:"name"`
+	Taken   time.Time               `json:"taken"`
+	Entries map[int32]baselineEntry `json:"entries"`
+}
+
+var currentBaseline *baseline
+
+// baselinePath, when set, is where baselines are saved and loaded from
+var baselinePath string
+
+var baselineHeaders = []string{"PID", "Name", "Read since", "Write since", "Status"}
+
+var baselineWidths = []int{8, 30, 14, 14, 10}
+
+// saveBaseline records the current counters of every process as the new
+// baseline, writing it to baselinePath when one is configured.
+func saveBaseline(processes []ProcessIO) error {
+	now := time.Now()
+	b := &baseline{
+		Name:    "baseline " + now.Format("15:04:05"),
+		Taken:   now,
+		Entries: make(map[int32]baselineEntry, len(processes)),
+	}
+	for _, p := range processes {
+		b.Entries[p.PID] = baselineEntry{Name: p.Name, CreateTime: p.CreateTime, Read: p.ReadBytes, Write: p.WriteBytes}
+	}
+	currentBaseline = b
+
+	if baselinePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(baselinePath, data, 0o644)
+}
+
+// loadBaseline reads a previously saved baseline. A missing file is not an
+// error, since the first save creates it.
+func loadBaseline(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	currentBaseline = &b
+	return nil
+}
+
+type baselineDelta struct {
+	ProcessIO
+	Read   float64
+	Write  float64
+	Status string
+}
+
+func baselineRows(processes []ProcessIO, limit int) [][]string {
+	deltas := make([]baselineDelta, 0, len(processes))
+	for _, p := range processes {
+		d := baselineDelta{ProcessIO: p, Read: p.ReadBytes, Write: p.WriteBytes, Status: "new"}
+		entry, ok := currentBaseline.Entries[p.PID]
+		if ok && entry.CreateTime == p.CreateTime && p.ReadBytes >= entry.Read && p.WriteBytes >= entry.Write {
+			d.Read -= entry.Read
+			d.Write -= entry.Write
+			d.Status = ""
+		}
+		deltas = append(deltas, d)
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		return deltas[i].Read+deltas[i].Write > deltas[j].Read+deltas[j].Write
+	})
+
+	rows := [][]string{baselineHeaders}
+	for _, d := range deltas[:min(limit, len(deltas))] {
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", d.PID),
+			displayName(d.ProcessIO),
+			humanizeBytes(d.Read),
+			humanizeBytes(d.Write),
+			d.Status,
+		})
+	}
+	return rows
+}
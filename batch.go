@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// batchRecord is one process in a -batch sample.
+type batchRecord struct {
+	Time       time.Time `json:"time"`
+	PID        int32     `json:"pid"`
+	Name       string    `json:"name"`
+	CPUPercent float64   `json:"cpu_percent"`
+	MemPercent float32   `json:"mem_percent"`
+	ReadRate   float64   `json:"read_bytes_per_sec"`
+	WriteRate  float64   `json:"write_bytes_per_sec"`
+}
+
+// batchFormats lists the -format values in flag order.
+var batchFormats = []string{"json", "csv", "table"}
+
+// createOutput creates the -output file, or truncates it when it exists.
+// An existing file keeps its permissions.
+func createOutput(path string) (*os.File, error) {
+	f, err := os.Create(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("can't create -output file %s: directory %s does not exist", path, filepath.Dir(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't create -output file: %w", err)
+	}
+	return f, nil
+}
+
+// runBatch writes one sample per interval to w until count samples have
+// been written, or forever when count is 0. json writes an array per line,
+// csv one record per process with the sample time first and a single
+// header, and table the same layout as a D dump. The first sample only
+// primes the snapshots, so every sample written has real rates and it
+// doesn't count towards count.
+func runBatch(w io.Writer, nextRefresh func() time.Duration, count, limit int, format string) error {
+	if _, err := getProcessesIO(); err != nil {
+		log.Printf("Error getting processes: %v", err)
+	}
+	if err := checkSkipLimit(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	cw := csv.NewWriter(w)
+	if format == "csv" {
+		cw.Write(append([]string{"time"}, csvHeader...))
+	}
+	for written := 0; count == 0 || written < count; {
+		time.Sleep(nextRefresh())
+		processes, err := getProcessesIO()
+		if err != nil {
+			log.Printf("Error getting processes: %v", err)
+			continue
+		}
+		if err := checkSkipLimit(); err != nil {
+			return err
+		}
+		processes = filterProcesses(processes)
+		if limit > 0 {
+			processes = processes[:min(limit, len(processes))]
+		}
+		if format != "json" {
+			loadAllOpenFiles(processes)
+		}
+
+		now := time.Now()
+		switch format {
+		case "csv":
+			for _, p := range processes {
+				cw.Write(append([]string{now.Format(time.RFC3339)}, csvRecord(p)...))
+			}
+			cw.Flush()
+			err = cw.Error()
+		case "table":
+			if _, err = fmt.Fprintf(w, "%s\n", now.Format(time.RFC3339)); err == nil {
+				if err = writeText(w, processes); err == nil {
+					_, err = fmt.Fprintln(w)
+				}
+			}
+		default:
+			records := make([]batchRecord, 0, len(processes))
+			for _, p := range processes {
+				records = append(records, batchRecord{
+					Time:       now,
+					PID:        p.PID,
+					Name:       p.Name,
+					CPUPercent: p.CPUPercent,
+					MemPercent: p.MemPercent,
+					ReadRate:   p.ReadRate,
+					WriteRate:  p.WriteRate,
+				})
+			}
+			err = enc.Encode(records)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write sample: %w", err)
+		}
+		written++
+	}
+	return nil
+}
+
+// runSummary writes one key=value line per sample to w with the system
+// totals and the busiest process, for watch loops and alerting scripts.
+// Like runBatch it primes the snapshots first and stops after count lines
+// unless count is 0.
+func runSummary(w io.Writer, nextRefresh func() time.Duration, count int) error {
+	if _, err := getProcessesIO(); err != nil {
+		log.Printf("Error getting processes: %v", err)
+	}
+	if err := checkSkipLimit(); err != nil {
+		return err
+	}
+	for written := 0; count == 0 || written < count; {
+		time.Sleep(nextRefresh())
+		processes, err := getProcessesIO()
+		if err != nil {
+			log.Printf("Error getting processes: %v", err)
+			continue
+		}
+		if err := checkSkipLimit(); err != nil {
+			return err
+		}
+
+		var top ProcessIO
+		for _, p := range filterProcesses(processes) {
+			if p.ReadRate+p.WriteRate > top.ReadRate+top.WriteRate {
+				top = p
+			}
+		}
+		line := fmt.Sprintf("time=%s read=%.0f write=%.0f", time.Now().Format(time.RFC3339), lastTotals.Read, lastTotals.Write)
+		if top.PID != 0 {
+			line += fmt.Sprintf(" top_pid=%d top_name=%q top_rate=%.0f", top.PID, top.Name, top.ReadRate+top.WriteRate)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+		written++
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+// sumChildren makes each process's I/O include that of all its descendants,
+// so supervisors whose workers do the actual I/O rank accordingly.
+var sumChildren bool
+
+func addChildrenIO(processes []ProcessIO) {
+	index := make(map[int32]int, len(processes))
+	for i, p := range processes {
+		index[p.PID] = i
+	}
+
+	// Snapshot own figures first so sums don't compound as parents are updated
+	own := make([]ProcessIO, len(processes))
+	copy(own, processes)
+
+	for _, child := range own {
+		parent, ok := index[child.PPID]
+		// The depth limit guards against PPID cycles from PID reuse
+		for depth := 0; ok && depth < len(processes); depth++ {
+			p := &processes[parent]
+			if p.PID == child.PID {
+				break
+			}
+			p.ReadRate += child.ReadRate
+			p.WriteRate += child.WriteRate
+			p.AvgRead += child.AvgRead
+			p.AvgWrite += child.AvgWrite
+			p.AccumRead += child.AccumRead
+			p.AccumWrite += child.AccumWrite
+			parent, ok = index[p.PPID]
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestAddChildrenIO(t *testing.T) {
+	// 1 is the parent of 2, which is the parent of 3. 4 is unrelated.
+	processes := []ProcessIO{
+		{PID: 1, PPID: 0, ReadRate: 1, WriteRate: 10, AccumRead: 100, AccumWrite: 1000},
+		{PID: 2, PPID: 1, ReadRate: 2, WriteRate: 20, AccumRead: 200, AccumWrite: 2000},
+		{PID: 3, PPID: 2, ReadRate: 4, WriteRate: 40, AccumRead: 400, AccumWrite: 4000},
+		{PID: 4, PPID: 0, ReadRate: 8, WriteRate: 80, AccumRead: 800, AccumWrite: 8000},
+	}
+	addChildrenIO(processes)
+
+	want := map[int32][4]float64{
+		1: {7, 70, 700, 7000},
+		2: {6, 60, 600, 6000},
+		3: {4, 40, 400, 4000},
+		4: {8, 80, 800, 8000},
+	}
+	for _, p := range processes {
+		got := [4]float64{p.ReadRate, p.WriteRate, p.AccumRead, p.AccumWrite}
+		if got != want[p.PID] {
+			t.Errorf("pid %d: read/s, write/s, accumulated read, write = %v, want %v", p.PID, got, want[p.PID])
+		}
+	}
+}
+
+func TestAddChildrenIOCycle(t *testing.T) {
+	// A reused PID can make two processes each other's parent
+	processes := []ProcessIO{
+		{PID: 1, PPID: 2, ReadRate: 1, AccumRead: 10},
+		{PID: 2, PPID: 1, ReadRate: 2, AccumRead: 20},
+	}
+	addChildrenIO(processes)
+	for _, p := range processes {
+		if p.ReadRate != 3 || p.AccumRead != 30 {
+			t.Errorf("pid %d: read/s %v, accumulated read %v, want 3 and 30", p.PID, p.ReadRate, p.AccumRead)
+		}
+	}
+}
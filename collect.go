@@ -0,0 +1,153 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// collectWorkers is how many processes are read from /proc at once.
+var collectWorkers = runtime.NumCPU()
+
+// procHandle is a gopsutil handle kept between samples. Reusing it makes
+// Percent measure CPU over the last interval instead of the process's whole
+// life. startTicks tells a reused PID apart, since gopsutil caches the
+// create time and name on the handle.
+type procHandle struct {
+	proc       *process.Process
+	startTicks uint64
+}
+
+var handles = make(map[int32]*procHandle)
+
+// procSample is everything one worker reads about one process. Workers only
+// touch /proc; snapshots, skips and file growth are updated afterwards on
+// the calling goroutine.
+type procSample struct {
+	handle       *procHandle
+	name         string
+	skipStage    string
+	skipErr      error
+	read, write  uint64
+	exe, cmdline string
+	container    string
+	nspid, ppid  int32
+	nice         int32
+	createTime   int64
+	cpuPercent   float64
+	memPercent   float32
+	threads      []ThreadInfo
+	faults       uint64
+	blkio        uint64
+	havePressure bool
+	ioPrio       int
+	filesLoaded  bool
+	files, paths []string
+}
+
+// collectSamples reads every PID over a pool of collectWorkers goroutines.
+// Samples come back in PID order; one with an empty name was filtered out
+// or skipped.
+func collectSamples(pids []int32) []procSample {
+	var totalMemory uint64
+	if vm, err := mem.VirtualMemory(); err == nil {
+		totalMemory = vm.Total
+	}
+	allFiles := needAllFiles()
+
+	samples := make([]procSample, len(pids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(collectWorkers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				samples[i] = readProcess(handles[pids[i]], pids[i], totalMemory, allFiles)
+			}
+		}()
+	}
+	for i := range pids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for pid := range handles {
+		delete(handles, pid)
+	}
+	for _, s := range samples {
+		if s.handle != nil {
+			handles[s.handle.proc.Pid] = s.handle
+		}
+	}
+	return samples
+}
+
+// readProcess samples one process, reusing h when it still belongs to the
+// same process.
+func readProcess(h *procHandle, pid int32, totalMemory uint64, allFiles bool) procSample {
+	ticks := startTicks(pid)
+	if h == nil || h.startTicks != ticks {
+		h = &procHandle{proc: &process.Process{Pid: pid}, startTicks: ticks}
+	}
+	p := h.proc
+	s := procSample{handle: h}
+
+	name, err := p.Name()
+	if err != nil {
+		s.skipStage, s.skipErr = "name", err
+		return s
+	}
+	if !collectProcess(p, name) {
+		return s
+	}
+
+	ioStats, err := p.IOCounters()
+	if err != nil {
+		s.skipStage, s.skipErr = "io counters", err
+		return s
+	}
+	s.read, s.write = ioStats.ReadBytes, ioStats.WriteBytes
+	if currentAccounting == AccountingVFS {
+		if s.read, s.write, err = vfsCounters(pid); err != nil {
+			s.skipStage, s.skipErr = "vfs counters", err
+			return s
+		}
+	}
+
+	if currentGroup == GroupByExe {
+		s.exe, _ = p.Exe()
+	}
+	if needCmdline() {
+		s.cmdline, _ = p.Cmdline()
+	}
+	if showNamespaces {
+		s.nspid = namespacePID(pid)
+	}
+	if needContainers() {
+		s.container = containerID(pid)
+	}
+
+	s.ppid, _ = p.Ppid()
+	s.nice, _ = p.Nice()
+	s.createTime, _ = p.CreateTime()
+	s.cpuPercent, _ = p.Percent(0)
+	if info, err := p.MemoryInfo(); err == nil && totalMemory > 0 {
+		s.memPercent = float32(100 * float64(info.RSS) / float64(totalMemory))
+	}
+	if allFiles {
+		s.files, s.paths = readOpenFiles(p)
+		s.filesLoaded = true
+	}
+	if showThreads {
+		s.threads = getThreads(p)
+	}
+	s.faults, s.blkio, s.havePressure = pressureCounters(pid)
+	s.ioPrio = ioPriority(pid)
+
+	s.name = name
+	return s
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// noSort marks columns that clicking doesn't sort by.
+const noSort SortBy = -1
+
+// column is one column of the process table. A zero Width is sized at
+// render time: Name fits the visible names and Open Files takes whatever
+// the other columns leave.
+type column struct {
+	Key    string
+	Header string
+	// AccumHeader replaces Header while accumulated bytes are shown
+	AccumHeader string
+	Width       int
+	Sort        SortBy
+	Cell        func(p ProcessIO) string
+}
+
+var allColumns = []column{
+	{Key: "pid", Header: "PID", Width: 8, Sort: SortByPID, Cell: func(p ProcessIO) string {
+		return fmt.Sprintf("%d", p.PID)
+	}},
+	{Key: "name", Header: "Name", Sort: SortByName, Cell: displayName},
+	{Key: "cpu", Header: "CPU%", Width: 8, Sort: SortByCPU, Cell: func(p ProcessIO) string {
+		return formatPercent(p.CPUPercent)
+	}},
+	{Key: "mem", Header: "MEM%", Width: 8, Sort: SortByMem, Cell: func(p ProcessIO) string {
+		return formatPercent(float64(p.MemPercent))
+	}},
+	{Key: "read", Header: "Read/s", AccumHeader: "Read", Width: 12, Sort: SortByRead, Cell: func(p ProcessIO) string {
+		if showAccumulated {
+			return humanizeBytes(p.AccumRead)
+		}
+		return humanizeBytes(p.ReadRate)
+	}},
+	{Key: "write", Header: "Write/s", AccumHeader: "Write", Width: 12, Sort: SortByWrite, Cell: func(p ProcessIO) string {
+		if showAccumulated {
+			return humanizeBytes(p.AccumWrite)
+		}
+		return humanizeBytes(p.WriteRate)
+	}},
+	{Key: "avg-read", Header: "Avg Read/s", Width: 12, Sort: SortByAvgRead, Cell: func(p ProcessIO) string {
+		return humanizeBytes(p.AvgRead)
+	}},
+	{Key: "avg-write", Header: "Avg Write/s", Width: 12, Sort: SortByAvgWrite, Cell: func(p ProcessIO) string {
+		return humanizeBytes(p.AvgWrite)
+	}},
+	{Key: "prio", Header: "PRIO", Width: 6, Sort: noSort, Cell: func(p ProcessIO) string {
+		return formatIOPrio(p.IOPrio)
+	}},
+	{Key: "container", Header: "Container", Width: 16, Sort: noSort, Cell: func(p ProcessIO) string {
+		return orDash(p.Container)
+	}},
+	{Key: "pressure", Header: "Pressure", Width: 10, Sort: SortByPressure, Cell: func(p ProcessIO) string {
+		return strconv.FormatFloat(p.Pressure, 'f', 2, 64)
+	}},
+	{Key: "trend", Header: "Trend", Width: historyLength, Sort: noSort, Cell: func(p ProcessIO) string {
+		return sparkline(p.History)
+	}},
+	{Key: "peak", Header: "%Peak", Width: 7, Sort: noSort, Cell: peakShare},
+	{Key: "files", Header: "Open Files", Sort: noSort, Cell: filesCell},
+}
+
+const defaultColumns = "pid,name,cpu,mem,read,write,avg-read,avg-write,prio,files"
+
+// tableColumns is the -columns layout, before the columns that keys toggle
+// are added.
+var tableColumns []column
+
+func columnKeys() []string {
+	keys := make([]string, len(allColumns))
+	for i, c := range allColumns {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+func lookupColumn(key string) (column, bool) {
+	for _, c := range allColumns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+// parseColumns reads a comma-separated column list. A column may be given a
+// fixed width as key:width.
+func parseColumns(s string) ([]column, error) {
+	var cols []column
+	for _, field := range strings.Split(s, ",") {
+		key, width, hasWidth := strings.Cut(strings.TrimSpace(field), ":")
+		c, ok := lookupColumn(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (want %s)", key, strings.Join(columnKeys(), ", "))
+		}
+		if columnIndex(cols, key) >= 0 {
+			return nil, fmt.Errorf("column %q is listed twice", key)
+		}
+		if hasWidth {
+			n, err := strconv.Atoi(width)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid width %q for column %q", width, key)
+			}
+			c.Width = n
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+func columnIndex(cols []column, key string) int {
+	return slices.IndexFunc(cols, func(c column) bool { return c.Key == key })
+}
+
+// visibleColumns is the -columns layout plus the columns switched on by
+// keys, which go before Open Files unless they are listed already.
+func visibleColumns() []column {
+	cols := slices.Clone(tableColumns)
+	at := columnIndex(cols, "files")
+	if at < 0 {
+		at = len(cols)
+	}
+	toggled := []struct {
+		key string
+		on  bool
+	}{
+		{"container", showContainers},
+		// Pressure has no column of its own outside this sort
+		{"pressure", currentSort == SortByPressure},
+		{"trend", showSparklines},
+		{"peak", showPeakShare},
+	}
+	for _, t := range toggled {
+		if !t.on || columnIndex(cols, t.key) >= 0 {
+			continue
+		}
+		c, _ := lookupColumn(t.key)
+		cols = slices.Insert(cols, at, c)
+		at++
+	}
+	return cols
+}
+
+// hasColumn reports whether the table currently shows the column key.
+func hasColumn(key string) bool {
+	return columnIndex(visibleColumns(), key) >= 0
+}
+
+// parseSort maps a sortable column key to its sort.
+func parseSort(s string) (SortBy, error) {
+	var keys []string
+	for _, c := range allColumns {
+		if c.Sort == noSort {
+			continue
+		}
+		if c.Key == s {
+			return c.Sort, nil
+		}
+		keys = append(keys, c.Key)
+	}
+	return SortByCPU, fmt.Errorf("unknown sort %q (want %s)", s, strings.Join(keys, ", "))
+}
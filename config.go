@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// envPrefix is prepended to a flag's upper-cased name, with dashes turned
+// into underscores, to form the environment variable that sets its default:
+// -interval-jitter is read from GO_IOTOP_INTERVAL_JITTER.
+const envPrefix = "GO_IOTOP_"
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// setFlags returns the names of the flags already set in fs, along with
+// the aliases sharing a variable with one of them, so a lower layer can't
+// override -interval through -delay.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	var values []flag.Value
+	fs.Visit(func(f *flag.Flag) {
+		values = append(values, f.Value)
+	})
+	set := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		for _, v := range values {
+			if sameValue(f.Value, v) {
+				set[f.Name] = true
+			}
+		}
+	})
+	return set
+}
+
+// sameValue reports whether two flags write to the same variable. The
+// standard flag values are pointers to it, so aliases compare equal.
+func sameValue(a, b flag.Value) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// applyEnv resolves option precedence: flags given on the command line win,
+// then environment variables, then the config file, then the built-in
+// defaults. It must run after fs.Parse.
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := setFlags(fs)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s=%q: %w", envName(f.Name), value, setErr)
+		}
+	})
+	return err
+}
+
+// defaultConfigPath is ~/.config/go-iotop/config.toml on Linux, or "" when
+// there is no config directory.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-iotop", "config.toml")
+}
+
+// theme holds the table colors, which the config file's [colors] table
+// can change.
+var theme = struct {
+	Text, Border, Header, Elevated, Selected, Warning ui.Color
+}{ui.ColorWhite, ui.ColorGreen, ui.ColorYellow, ui.ColorMagenta, ui.ColorWhite, ui.ColorRed}
+
+var colorNames = map[string]ui.Color{
+	"default": ui.ColorClear,
+	"black":   ui.ColorBlack,
+	"red":     ui.ColorRed,
+	"green":   ui.ColorGreen,
+	"yellow":  ui.ColorYellow,
+	"blue":    ui.ColorBlue,
+	"magenta": ui.ColorMagenta,
+	"cyan":    ui.ColorCyan,
+	"white":   ui.ColorWhite,
+}
+
+// parseColor accepts a color name or a 256-color palette index.
+func parseColor(s string) (ui.Color, error) {
+	if c, ok := colorNames[s]; ok {
+		return c, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < 256 {
+		return ui.Color(n), nil
+	}
+	return 0, fmt.Errorf("unknown color %q", s)
+}
+
+// applyConfig sets the defaults of flags not given on the command line or
+// in the environment from the config file at path. Top-level keys are flag
+// names, as in
+//
+//	interval = "2s"
+//	columns = ["pid", "name", "read", "write", "files"]
+//	sort = "write"
+//
+//	[colors]
+//	header = "cyan"
+//
+// A missing file is only an error when required is set. It must run after
+// applyEnv.
+func applyConfig(fs *flag.FlagSet, path string, required bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	values, err := parseConfig(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	set := setFlags(fs)
+	for _, v := range values {
+		if color, ok := strings.CutPrefix(v.key, "colors."); ok {
+			if err := setThemeColor(color, v.value); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, v.line, err)
+			}
+			continue
+		}
+		if fs.Lookup(v.key) == nil {
+			return fmt.Errorf("%s:%d: unknown option %q", path, v.line, v.key)
+		}
+		if set[v.key] {
+			continue
+		}
+		if err := fs.Set(v.key, v.value); err != nil {
+			return fmt.Errorf("%s:%d: %s = %q: %w", path, v.line, v.key, v.value, err)
+		}
+	}
+	return nil
+}
+
+func setThemeColor(name, value string) error {
+	c, err := parseColor(value)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "text":
+		theme.Text = c
+	case "border":
+		theme.Border = c
+	case "header":
+		theme.Header = c
+	case "elevated":
+		theme.Elevated = c
+	case "selected":
+		theme.Selected = c
+	case "warning":
+		theme.Warning = c
+	default:
+		return fmt.Errorf("unknown color %q (want text, border, header, elevated, selected or warning)", name)
+	}
+	return nil
+}
+
+type configValue struct {
+	key   string
+	value string
+	line  int
+}
+
+// parseConfig reads the subset of TOML the config file needs: tables,
+// comments, and keys set to strings, numbers, booleans or arrays of
+// strings. Keys inside a table are prefixed with its name and a dot, and
+// arrays are joined with commas the way list flags expect.
+func parseConfig(r io.Reader) ([]configValue, error) {
+	var values []configValue
+	var table string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "["); ok {
+			name, ok = strings.CutSuffix(name, "]")
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("line %d: malformed table header", n)
+			}
+			table = strings.TrimSpace(name) + "."
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value, err := parseConfigValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		values = append(values, configValue{key: table + key, value: value, line: n})
+	}
+	return values, scanner.Err()
+}
+
+// stripComment drops a # comment unless it is inside a quoted string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseConfigValue(raw string) (string, error) {
+	if inner, ok := strings.CutPrefix(raw, "["); ok {
+		inner, ok = strings.CutSuffix(inner, "]")
+		if !ok {
+			return "", errors.New("unterminated array")
+		}
+		var items []string
+		for _, item := range strings.Split(inner, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			s, err := parseConfigValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	}
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("unterminated string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw == "":
+		return "", errors.New("missing value")
+	}
+	// Numbers and booleans are passed to the flag as written
+	return raw, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		config       string
+		wantInterval time.Duration
+		wantTop      int
+	}{
+		{
+			name:         "defaults",
+			wantInterval: time.Second,
+			wantTop:      10,
+		},
+		{
+			name:         "file over default",
+			config:       "interval = \"3s\"\ntop = 5\n",
+			wantInterval: 3 * time.Second,
+			wantTop:      5,
+		},
+		{
+			name:         "env over file",
+			env:          map[string]string{"GO_IOTOP_INTERVAL": "2s"},
+			config:       "interval = \"3s\"\ntop = 5\n",
+			wantInterval: 2 * time.Second,
+			wantTop:      5,
+		},
+		{
+			name:         "flag over env",
+			args:         []string{"-interval", "4s"},
+			env:          map[string]string{"GO_IOTOP_INTERVAL": "2s", "GO_IOTOP_TOP": "7"},
+			wantInterval: 4 * time.Second,
+			wantTop:      7,
+		},
+		{
+			name:         "alias flag over env",
+			args:         []string{"-delay", "4s"},
+			env:          map[string]string{"GO_IOTOP_INTERVAL": "2s"},
+			wantInterval: 4 * time.Second,
+			wantTop:      10,
+		},
+		{
+			name:         "flag over alias env",
+			args:         []string{"-interval", "4s"},
+			env:          map[string]string{"GO_IOTOP_DELAY": "2s"},
+			wantInterval: 4 * time.Second,
+			wantTop:      10,
+		},
+		{
+			name:         "alias env over file",
+			env:          map[string]string{"GO_IOTOP_DELAY": "2s"},
+			config:       "interval = \"3s\"\n",
+			wantInterval: 2 * time.Second,
+			wantTop:      10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			fs := flag.NewFlagSet("go-iotop", flag.ContinueOnError)
+			var interval time.Duration
+			fs.DurationVar(&interval, "interval", time.Second, "")
+			fs.DurationVar(&interval, "delay", time.Second, "")
+			top := fs.Int("top", 10, "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := applyEnv(fs); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if tt.config != "" {
+				if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := applyConfig(fs, path, false); err != nil {
+				t.Fatal(err)
+			}
+			if interval != tt.wantInterval {
+				t.Errorf("interval = %v, want %v", interval, tt.wantInterval)
+			}
+			if *top != tt.wantTop {
+				t.Errorf("top = %d, want %d", *top, tt.wantTop)
+			}
+		})
+	}
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	t.Setenv("GO_IOTOP_TOP", "lots")
+	fs := flag.NewFlagSet("go-iotop", flag.ContinueOnError)
+	fs.Int("top", 10, "")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	err := applyEnv(fs)
+	if err == nil {
+		t.Fatal("applyEnv accepted GO_IOTOP_TOP=lots")
+	}
+	if !strings.Contains(err.Error(), "GO_IOTOP_TOP") {
+		t.Errorf("error %q doesn't name the variable", err)
+	}
+}
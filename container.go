@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// showContainers adds a column naming the container each process runs in
+var showContainers bool
+
+// dockerSocket is where container names are looked up. Containers Docker
+// doesn't know about, such as those run by containerd or CRI-O directly,
+// are shown by short ID.
+var dockerSocket = "/var/run/docker.sock"
+
+var (
+	containerNames = make(map[string]string)
+	dockerDown     bool
+)
+
+var dockerClient = &http.Client{
+	Timeout: 500 * time.Millisecond,
+	Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", dockerSocket)
+		},
+	},
+}
+
+// needContainers reports whether samples should resolve containers.
+func needContainers() bool {
+	return showContainers || currentGroup == GroupByContainer || columnIndex(tableColumns, "container") >= 0
+}
+
+// containerName returns the name of the container with the given ID, or
+// its first 12 digits when it has none. Each ID is looked up once, and
+// Docker is not asked again after it fails to answer.
+func containerName(id string) string {
+	if id == "" {
+		return ""
+	}
+	if name, ok := containerNames[id]; ok {
+		return name
+	}
+	name := id[:12]
+	if !dockerDown {
+		if n, err := dockerContainerName(id); err == nil {
+			name = n
+		} else if _, notFound := err.(dockerStatusError); !notFound {
+			dockerDown = true
+		}
+	}
+	containerNames[id] = name
+	return name
+}
+
+type dockerStatusError int
+
+func (e dockerStatusError) Error() string {
+	return fmt.Sprintf("docker returned status %d", int(e))
+}
+
+func dockerContainerName(id string) (string, error) {
+	resp, err := dockerClient.Get("http://docker/containers/" + id + "/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", dockerStatusError(resp.StatusCode)
+	}
+	var info struct {
+		Name string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	if info.Name == "" {
+		return "", dockerStatusError(resp.StatusCode)
+	}
+	return strings.TrimPrefix(info.Name, "/"), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// containerIDPattern matches the 64 hex digit IDs Docker, containerd, CRI-O
+// and Podman put in cgroup paths, such as docker-<id>.scope or
+// /kubepods/burstable/pod<uid>/<id>.
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// containerID returns the ID of the container a process runs in, or "" for
+// processes on the host.
+func containerID(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+	// The innermost ID wins when containers are nested
+	ids := containerIDPattern.FindAll(data, -1)
+	if len(ids) == 0 {
+		return ""
+	}
+	return string(ids[len(ids)-1])
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// containerID is only implemented on Linux.
+func containerID(pid int32) string {
+	return ""
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// processDetail describes everything known about a process for the detail
+// view. Fields that aren't sampled every interval are read on demand.
+func processDetail(p ProcessIO) string {
+	proc, err := process.NewProcess(p.PID)
+	if err != nil {
+		return fmt.Sprintf("PID %d has exited", p.PID)
+	}
+	cmdline := p.Cmdline
+	if cmdline == "" {
+		cmdline, _ = proc.Cmdline()
+	}
+	exe := p.Exe
+	if exe == "" {
+		exe, _ = proc.Exe()
+	}
+	cwd, _ := proc.Cwd()
+	fds := "-"
+	if n, err := proc.NumFDs(); err == nil {
+		fds = fmt.Sprintf("%d", n)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "PID %d  PPID %d  Nice %d  Started %s\n", p.PID, p.PPID, p.Nice,
+		time.UnixMilli(p.CreateTime).Format(time.DateTime))
+	fmt.Fprintf(&b, "Name:    %s\n", p.Name)
+	fmt.Fprintf(&b, "Exe:     %s\n", orDash(exe))
+	fmt.Fprintf(&b, "Cmdline: %s\n", orDash(cmdline))
+	fmt.Fprintf(&b, "Cwd:     %s\n", orDash(cwd))
+	if id := containerID(p.PID); id != "" {
+		fmt.Fprintf(&b, "Container: %s (%s)\n", containerName(id), id[:12])
+	}
+	fmt.Fprintf(&b, "CPU %s%%  MEM %s%%  FDs %s\n", formatPercent(p.CPUPercent), formatPercent(float64(p.MemPercent)), fds)
+	fmt.Fprintf(&b, "I/O (%s): read %s (%s/s), written %s (%s/s)\n", currentAccounting.Label(),
+		humanizeBytes(p.ReadBytes), humanizeBytes(p.ReadRate), humanizeBytes(p.WriteBytes), humanizeBytes(p.WriteRate))
+	if read, write, err := vfsCounters(p.PID); err == nil && currentAccounting != AccountingVFS {
+		fmt.Fprintf(&b, "Syscall I/O: read %s, written %s\n", humanizeBytes(float64(read)), humanizeBytes(float64(write)))
+	}
+
+	threads := getThreads(proc)
+	names := make([]string, 0, len(threads))
+	for _, t := range threads {
+		names = append(names, fmt.Sprintf("%d %s", t.TID, t.Name))
+	}
+	fmt.Fprintf(&b, "Threads: %s\n", joinOrDash(names))
+	fmt.Fprintf(&b, "Open files (%d):\n", len(p.OpenFiles))
+	for _, f := range p.OpenFiles {
+		fmt.Fprintf(&b, "  %s\n", f)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
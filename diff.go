@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// diffKey identifies a process across recordings, where PIDs differ.
+type diffKey struct {
+	Name    string
+	Cmdline string
+}
+
+// diffRates are the mean read and write rates of a process over a
+// recording, counting the frames it is missing from as idle.
+type diffRates struct {
+	Read  float64
+	Write float64
+}
+
+// diffRow compares one process between the two recordings.
+type diffRow struct {
+	Key  diffKey
+	A, B diffRates
+}
+
+func (r diffRow) delta() float64 {
+	return (r.B.Read + r.B.Write) - (r.A.Read + r.A.Write)
+}
+
+// loadDiffSession reads every frame of a -record file and returns the mean
+// rates per process. hasCmdline reports whether command lines were
+// recorded.
+func loadDiffSession(path string) (rates map[diffKey]diffRates, hasCmdline bool, err error) {
+	r, err := openReplay(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.f.Close()
+	rates = make(map[diffKey]diffRates)
+	for i := range r.offsets {
+		if err := r.seek(i); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, p := range r.frame.Processes {
+			key := diffKey{p.Name, p.Cmdline}
+			hasCmdline = hasCmdline || p.Cmdline != ""
+			sum := rates[key]
+			sum.Read += p.ReadRate
+			sum.Write += p.WriteRate
+			rates[key] = sum
+		}
+	}
+	frames := float64(len(r.offsets))
+	for key, sum := range rates {
+		rates[key] = diffRates{sum.Read / frames, sum.Write / frames}
+	}
+	return rates, hasCmdline, nil
+}
+
+// diffSessions matches the processes of two recordings by name and command
+// line, or by name alone unless both recorded command lines, and sorts them
+// by how much their combined rate changed, largest first.
+func diffSessions(pathA, pathB string) ([]diffRow, error) {
+	a, cmdA, err := loadDiffSession(pathA)
+	if err != nil {
+		return nil, err
+	}
+	b, cmdB, err := loadDiffSession(pathB)
+	if err != nil {
+		return nil, err
+	}
+	rows := make(map[diffKey]*diffRow)
+	add := func(rates map[diffKey]diffRates, side func(*diffRow) *diffRates) {
+		for key, r := range rates {
+			if !cmdA || !cmdB {
+				key.Cmdline = ""
+			}
+			row := rows[key]
+			if row == nil {
+				row = &diffRow{Key: key}
+				rows[key] = row
+			}
+			s := side(row)
+			s.Read += r.Read
+			s.Write += r.Write
+		}
+	}
+	add(a, func(r *diffRow) *diffRates { return &r.A })
+	add(b, func(r *diffRow) *diffRates { return &r.B })
+
+	sorted := make([]diffRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, *row)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		di, dj := math.Abs(sorted[i].delta()), math.Abs(sorted[j].delta())
+		if di != dj {
+			return di > dj
+		}
+		if sorted[i].Key.Name != sorted[j].Key.Name {
+			return sorted[i].Key.Name < sorted[j].Key.Name
+		}
+		return sorted[i].Key.Cmdline < sorted[j].Key.Cmdline
+	})
+	return sorted, nil
+}
+
+// writeDiff prints rows as a table of mean rates per second, leaving out
+// processes that did no I/O in either recording.
+func writeDiff(w io.Writer, rows []diffRow) error {
+	signed := func(v float64) string {
+		if v < 0 {
+			return "-" + humanizeBytes(-v)
+		}
+		return "+" + humanizeBytes(v)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DELTA/s\tREAD/s A\tREAD/s B\tWRITE/s A\tWRITE/s B\tPROCESS")
+	for _, r := range rows {
+		if r.A == (diffRates{}) && r.B == (diffRates{}) {
+			continue
+		}
+		process := r.Key.Name
+		if r.Key.Cmdline != "" {
+			process = r.Key.Cmdline
+		}
+		fmt.Fprintln(tw, strings.Join([]string{
+			signed(r.delta()),
+			humanizeBytes(r.A.Read), humanizeBytes(r.B.Read),
+			humanizeBytes(r.A.Write), humanizeBytes(r.B.Write),
+			process,
+		}, "\t"))
+	}
+	return tw.Flush()
+}
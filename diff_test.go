@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffSessions(t *testing.T) {
+	rows, err := diffSessions("testdata/diff-before.ndjson", "testdata/diff-after.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []diffRow{
+		{diffKey{"backup", "backup --full"}, diffRates{}, diffRates{2097152, 0}},
+		// Missing from one of the two frames, so half its rate
+		{diffKey{"rsync", "rsync -a /src /dst"}, diffRates{524288, 524288}, diffRates{}},
+		{diffKey{"postgres", "postgres -D /var/lib/pg"}, diffRates{4096, 8192}, diffRates{2048, 1024}},
+		{diffKey{"sshd", "sshd -D"}, diffRates{}, diffRates{}},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	var b strings.Builder
+	if err := writeDiff(&b, rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header and 3 rows without the idle sshd:\n%s", len(lines), b.String())
+	}
+	for i, prefix := range []string{"DELTA", "+2.00 MB", "-1.00 MB", "-9.00 KB"} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("line %d = %q, want it to start with %q", i, lines[i], prefix)
+		}
+	}
+	if !strings.HasSuffix(lines[1], "backup --full") {
+		t.Errorf("line 1 = %q, want the command line last", lines[1])
+	}
+}
+
+// A recording made without command lines is matched by name alone.
+func TestDiffSessionsByName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.ndjson")
+	frame := `{"time":"2026-10-03T10:00:00Z","processes":[{"PID":7,"Name":"postgres","ReadRate":1024,"WriteRate":0}]}` + "\n"
+	if err := os.WriteFile(path, []byte(frame), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := diffSessions("testdata/diff-before.ndjson", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if r.Key.Cmdline != "" {
+			t.Errorf("row %+v kept its command line", r)
+		}
+		if r.Key.Name == "postgres" && (r.A != diffRates{4096, 8192} || r.B != diffRates{1024, 0}) {
+			t.Errorf("postgres = %+v, want the two recordings matched by name", r)
+		}
+	}
+}
+
+func TestDiffSessionsBadFile(t *testing.T) {
+	if _, err := diffSessions("testdata/diff-before.ndjson", filepath.Join(t.TempDir(), "missing.ndjson")); err == nil {
+		t.Error("diffSessions with a missing file succeeded")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// isDirectIO is only implemented on Linux.
+func isDirectIO(pid int32, fd uint64) bool {
+	return false
+}
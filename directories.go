@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dirDepth is how many leading path components name a directory in the
+// directory view, so 2 groups /var/lib/postgresql/... under /var/lib.
+var dirDepth = 2
+
+var directoryHeaders = []string{"Directory", "Read/s", "Write/s", "Processes"}
+
+var directoryWidths = []int{40, 14, 14, 10}
+
+type dirEntry struct {
+	Dir       string
+	Read      float64
+	Write     float64
+	Processes int
+}
+
+// fileDirectory returns the directory a path is attributed to, or "" for
+// entries that are not regular files on disk.
+func fileDirectory(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch parts[0] {
+	case "proc", "sys", "dev":
+		return ""
+	}
+	// Drop the file name itself
+	parts = parts[:len(parts)-1]
+	if len(parts) > dirDepth {
+		parts = parts[:dirDepth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// directoryRows splits each process's rates evenly across the distinct
+// directories of its open files. The kernel does not report I/O per file,
+// so this is only an estimate of where the traffic lands.
+func directoryRows(processes []ProcessIO, limit int) [][]string {
+	dirs := make(map[string]*dirEntry)
+	for _, p := range processes {
+		if p.ReadRate+p.WriteRate == 0 {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, path := range p.Paths {
+			if dir := fileDirectory(path); dir != "" {
+				seen[dir] = true
+			}
+		}
+		if len(seen) == 0 {
+			continue
+		}
+		share := float64(len(seen))
+		for dir := range seen {
+			entry, ok := dirs[dir]
+			if !ok {
+				entry = &dirEntry{Dir: dir}
+				dirs[dir] = entry
+			}
+			entry.Read += p.ReadRate / share
+			entry.Write += p.WriteRate / share
+			entry.Processes++
+		}
+	}
+
+	entries := make([]*dirEntry, 0, len(dirs))
+	for _, e := range dirs {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Read+entries[i].Write != entries[j].Read+entries[j].Write {
+			return entries[i].Read+entries[i].Write > entries[j].Read+entries[j].Write
+		}
+		return entries[i].Dir < entries[j].Dir
+	})
+
+	rows := [][]string{directoryHeaders}
+	for _, e := range entries[:min(limit, len(entries))] {
+		rows = append(rows, []string{
+			e.Dir,
+			humanizeBytes(e.Read),
+			humanizeBytes(e.Write),
+			fmt.Sprintf("%d", e.Processes),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskStat is a block device's activity over the last sampling interval.
+// Until a device has been sampled twice only its name is known.
+type diskStat struct {
+	Name       string
+	ReadRate   float64
+	WriteRate  float64
+	ReadIOPS   float64
+	WriteIOPS  float64
+	Util       float64
+	QueueDepth float64
+	Valid      bool
+}
+
+var (
+	lastDiskCounters map[string]disk.IOCountersStat
+	lastDiskSample   time.Time
+	// diskStats holds the devices of the latest sample, busiest first
+	diskStats []diskStat
+)
+
+// sampleDisks diffs the kernel's per-device counters against the previous
+// sample. IoTime and WeightedIO are in milliseconds, so dividing their
+// deltas by the elapsed milliseconds gives utilization and the average
+// number of requests in flight.
+func sampleDisks() {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	elapsed := now.Sub(lastDiskSample)
+
+	diskStats = diskStats[:0]
+	for name, c := range counters {
+		if c.ReadCount+c.WriteCount == 0 {
+			// Never used, e.g. unattached loop devices
+			continue
+		}
+		stat := diskStat{Name: name}
+		if prev, ok := lastDiskCounters[name]; ok && elapsed > 0 && c.IoTime >= prev.IoTime {
+			ms := elapsed.Seconds() * 1000
+			stat.ReadRate = float64(c.ReadBytes-prev.ReadBytes) / elapsed.Seconds()
+			stat.WriteRate = float64(c.WriteBytes-prev.WriteBytes) / elapsed.Seconds()
+			stat.ReadIOPS = float64(c.ReadCount-prev.ReadCount) / elapsed.Seconds()
+			stat.WriteIOPS = float64(c.WriteCount-prev.WriteCount) / elapsed.Seconds()
+			stat.Util = math.Min(float64(c.IoTime-prev.IoTime)/ms*100, 100)
+			stat.QueueDepth = float64(c.WeightedIO-prev.WeightedIO) / ms
+			stat.Valid = true
+		}
+		diskStats = append(diskStats, stat)
+	}
+	sort.Slice(diskStats, func(i, j int) bool {
+		if diskStats[i].Util != diskStats[j].Util {
+			return diskStats[i].Util > diskStats[j].Util
+		}
+		return diskStats[i].Name < diskStats[j].Name
+	})
+	lastDiskCounters = counters
+	lastDiskSample = now
+}
+
+func (d diskStat) String() string {
+	if !d.Valid {
+		return d.Name + " -"
+	}
+	return fmt.Sprintf("%s %s%% q%.2f R %s/s W %s/s", d.Name, formatPercent(d.Util), d.QueueDepth,
+		humanizeBytes(d.ReadRate), humanizeBytes(d.WriteRate))
+}
+
+// showDiskPanel adds a table of every block device above the process table
+var showDiskPanel bool
+
+// maxDiskRows caps how many devices the panel lists, busiest first
+const maxDiskRows = 8
+
+var diskHeaders = []string{"Device", "Read/s", "Write/s", "Read IOPS", "Write IOPS", "Util%", "Queue"}
+
+func diskRows(limit int) [][]string {
+	rows := [][]string{diskHeaders}
+	for _, d := range diskStats[:min(limit, len(diskStats))] {
+		if !d.Valid {
+			rows = append(rows, []string{d.Name, "-", "-", "-", "-", "-", "-"})
+			continue
+		}
+		rows = append(rows, []string{
+			d.Name,
+			humanizeBytes(d.ReadRate),
+			humanizeBytes(d.WriteRate),
+			fmt.Sprintf("%.0f", d.ReadIOPS),
+			fmt.Sprintf("%.0f", d.WriteIOPS),
+			formatPercent(d.Util),
+			fmt.Sprintf("%.2f", d.QueueDepth),
+		})
+	}
+	return rows
+}
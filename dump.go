@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// version is stamped at build time with -ldflags "-X main.version=v1.2.3".
+// Unstamped builds fall back to the module version, if any.
+var version = "dev"
+
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// dumpProcesses writes every process, in display order, to a timestamped
+// file in dir and returns its path. format is "csv" or "text".
+func dumpProcesses(processes []ProcessIO, dir, format string) (string, error) {
+	ext := "txt"
+	if format == "csv" {
+		ext = "csv"
+	}
+	path := filepath.Join(dir, fmt.Sprintf("go-iotop-%s.%s", time.Now().Format("20060102-150405"), ext))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if format == "csv" {
+		err = writeCSV(f, processes)
+	} else {
+		err = writeText(f, processes)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return path, err
+}
+
+var csvHeader = []string{"pid", "name", "cpu_percent", "mem_percent", "read_bytes_per_sec", "write_bytes_per_sec",
+	"read_bytes", "write_bytes", "open_files"}
+
+func csvRecord(p ProcessIO) []string {
+	return []string{
+		strconv.Itoa(int(p.PID)),
+		p.Name,
+		strconv.FormatFloat(p.CPUPercent, 'f', -1, 64),
+		strconv.FormatFloat(float64(p.MemPercent), 'f', -1, 32),
+		strconv.FormatFloat(p.ReadRate, 'f', -1, 64),
+		strconv.FormatFloat(p.WriteRate, 'f', -1, 64),
+		strconv.FormatFloat(p.ReadBytes, 'f', -1, 64),
+		strconv.FormatFloat(p.WriteBytes, 'f', -1, 64),
+		strings.Join(p.OpenFiles, ";"),
+	}
+}
+
+func writeCSV(f io.Writer, processes []ProcessIO) error {
+	w := csv.NewWriter(f)
+	w.Write(csvHeader)
+	for _, p := range processes {
+		w.Write(csvRecord(p))
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeText(f io.Writer, processes []ProcessIO) error {
+	tw := tabwriter.NewWriter(f, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headerRow(), "\t"))
+	filesCol := columnIndex(visibleColumns(), "files")
+	for _, p := range processes {
+		row := processRow(p)
+		if filesCol >= 0 {
+			row[filesCol] = strings.Join(p.OpenFiles, ", ")
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// capture is everything a bug report needs about one frozen sample.
+type capture struct {
+	Version    string      `json:"version"`
+	Time       time.Time   `json:"time"`
+	Host       string      `json:"host"`
+	Note       string      `json:"note,omitempty"`
+	Accounting string      `json:"accounting"`
+	Interval   string      `json:"interval"`
+	CPUPercent float64     `json:"cpu_percent"`
+	MemPercent float64     `json:"mem_percent"`
+	Load       []float64   `json:"load,omitempty"`
+	Processes  []ProcessIO `json:"processes"`
+}
+
+// writeCapture saves processes, system stats and a note as JSON to a
+// timestamped file in dir and returns its path.
+func writeCapture(processes []ProcessIO, dir, note string) (string, error) {
+	c := capture{
+		Version:    buildVersion(),
+		Time:       time.Now(),
+		Note:       note,
+		Accounting: ioLabel(),
+		Interval:   refreshInterval.String(),
+		CPUPercent: lastCPUPercent,
+		Processes:  processes,
+	}
+	c.Host, _ = os.Hostname()
+	if vm, err := mem.VirtualMemory(); err == nil {
+		c.MemPercent = vm.UsedPercent
+	}
+	if avg, err := load.Avg(); err == nil {
+		c.Load = []float64{avg.Load1, avg.Load5, avg.Load15}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("go-iotop-capture-%s.json", c.Time.Format("20060102-150405")))
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o644)
+}
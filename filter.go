@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// elevatedOnly hides processes that are not running at a negative nice value
+var elevatedOnly bool
+
+// changedOnly hides processes whose raw byte counters did not move since the
+// previous sample, however small the change would be as a rate
+var changedOnly bool
+
+// activeOnly hides processes with zero read and write rates, like iotop -o
+var activeOnly bool
+
+// minCPU hides processes using less than this CPU percentage
+var minCPU float64
+
+// nameFilter keeps processes whose name or command line contains it,
+// ignoring case, or whose PID is exactly it
+var nameFilter string
+
+// filtering is set while the / prompt is open. Samples then carry command
+// lines, so what is typed matches them as it is typed.
+var filtering bool
+
+func needCmdline() bool {
+	return showCmdline || metricsAddr != "" || nameFilter != "" || filtering
+}
+
+// loadCmdlines reads the command lines the last sample didn't collect, so
+// the / prompt matches them before the next sample comes in.
+func loadCmdlines(processes []ProcessIO) {
+	for i := range processes {
+		if h := handles[processes[i].PID]; h != nil && processes[i].Cmdline == "" {
+			processes[i].Cmdline, _ = h.proc.Cmdline()
+		}
+	}
+}
+
+func matchesNameFilter(p ProcessIO) bool {
+	if pid, err := strconv.ParseInt(nameFilter, 10, 32); err == nil && int32(pid) == p.PID {
+		return true
+	}
+	filter := strings.ToLower(nameFilter)
+	return strings.Contains(strings.ToLower(p.Name), filter) || strings.Contains(strings.ToLower(p.Cmdline), filter)
+}
+
+// filterProcesses keeps the processes matching every active filter.
+func filterProcesses(processes []ProcessIO) []ProcessIO {
+	if !elevatedOnly && !changedOnly && !activeOnly && minCPU <= 0 && nameFilter == "" {
+		return processes
+	}
+
+	filtered := make([]ProcessIO, 0, len(processes))
+	for _, p := range processes {
+		if elevatedOnly && p.Nice >= 0 {
+			continue
+		}
+		if changedOnly && !p.Changed {
+			continue
+		}
+		if activeOnly && p.ReadRate+p.WriteRate == 0 {
+			continue
+		}
+		if p.CPUPercent < minCPU {
+			continue
+		}
+		if nameFilter != "" && !matchesNameFilter(p) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
+
+// collectPIDs, collectUsers and collectName restrict which processes are
+// sampled at all, as set by -pid, -user and -name. Unlike the filters
+// above they also keep the other processes out of totals, the leaderboard
+// and exports.
+var (
+	collectPIDs  map[int32]bool
+	collectUsers map[string]bool
+	collectName  string
+)
+
+func parseCollectPIDs(s string) error {
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		pid, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid -pid %q: want a comma-separated list of PIDs", s)
+		}
+		if collectPIDs == nil {
+			collectPIDs = make(map[int32]bool)
+		}
+		collectPIDs[int32(pid)] = true
+	}
+	return nil
+}
+
+func parseCollectUsers(s string) {
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		if collectUsers == nil {
+			collectUsers = make(map[string]bool)
+		}
+		collectUsers[field] = true
+	}
+}
+
+// collectProcess reports whether a process passes the -user and -name
+// restrictions. The PID list is applied before sampling starts.
+func collectProcess(p *process.Process, name string) bool {
+	if collectName != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(collectName)) {
+		return false
+	}
+	if collectUsers != nil {
+		user, err := p.Username()
+		if err != nil || !collectUsers[user] {
+			return false
+		}
+	}
+	return true
+}
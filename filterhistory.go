@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxFilterHistory is how many / filters are remembered
+const maxFilterHistory = 100
+
+// filterHistory holds the filters applied at the / prompt, oldest first
+var filterHistory []string
+
+// filterHistoryPath is ~/.config/go-iotop/filter_history on Linux, next to
+// the config file, or "" when there is no config directory.
+func filterHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-iotop", "filter_history")
+}
+
+// loadFilterHistory reads one filter per line. A missing file is not an
+// error, since the first applied filter creates it.
+func loadFilterHistory() error {
+	path := filterHistoryPath()
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			filterHistory = append(filterHistory, line)
+		}
+	}
+	if len(filterHistory) > maxFilterHistory {
+		filterHistory = filterHistory[len(filterHistory)-maxFilterHistory:]
+	}
+	return nil
+}
+
+// addFilterHistory moves filter to the end of the history, dropping an
+// earlier copy of it, and saves the history.
+func addFilterHistory(filter string) error {
+	if i := slices.Index(filterHistory, filter); i >= 0 {
+		filterHistory = slices.Delete(filterHistory, i, i+1)
+	}
+	filterHistory = append(filterHistory, filter)
+	if len(filterHistory) > maxFilterHistory {
+		filterHistory = filterHistory[len(filterHistory)-maxFilterHistory:]
+	}
+
+	path := filterHistoryPath()
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(filterHistory, "\n")+"\n"), 0o644)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type GroupBy int
+
+const (
+	GroupNone GroupBy = iota
+	GroupByName
+	GroupByExe
+	GroupByContainer
+)
+
+var currentGroup GroupBy
+
+var expandGroups bool
+
+// ProcessGroup aggregates processes sharing the same grouping key.
+type ProcessGroup struct {
+	Key     string
+	Total   ProcessIO
+	Members []ProcessIO
+}
+
+func parseGroupBy(s string) (GroupBy, error) {
+	switch s {
+	case "", "none":
+		return GroupNone, nil
+	case "name":
+		return GroupByName, nil
+	case "exe":
+		return GroupByExe, nil
+	case "container":
+		return GroupByContainer, nil
+	}
+	return GroupNone, fmt.Errorf("unknown grouping %q (want none, name, exe or container)", s)
+}
+
+func groupKey(p ProcessIO, by GroupBy) string {
+	if by == GroupByContainer {
+		if p.Container != "" {
+			return p.Container
+		}
+		return "[host]"
+	}
+	if by == GroupByExe {
+		if p.Exe != "" {
+			return p.Exe
+		}
+		// Kernel threads and processes we can't inspect have no executable
+		return "[" + p.Name + "]"
+	}
+	return p.Name
+}
+
+// add sums the counters, rates and usage of p into t.
+func (t *ProcessIO) add(p ProcessIO) {
+	t.ReadBytes += p.ReadBytes
+	t.WriteBytes += p.WriteBytes
+	t.AccumRead += p.AccumRead
+	t.AccumWrite += p.AccumWrite
+	t.ReadRate += p.ReadRate
+	t.WriteRate += p.WriteRate
+	t.AvgRead += p.AvgRead
+	t.AvgWrite += p.AvgWrite
+	t.CPUPercent += p.CPUPercent
+	t.MemPercent += p.MemPercent
+	t.Pressure += p.Pressure
+}
+
+// groupProcesses sums the stats of processes sharing a key. Groups are
+// ordered by the active sort key applied to their totals, and members keep
+// the order they had in processes.
+func groupProcesses(processes []ProcessIO, by GroupBy) []ProcessGroup {
+	index := make(map[string]int)
+	var groups []ProcessGroup
+	for _, p := range processes {
+		key := groupKey(p, by)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			// A group sorts by PID as its lowest member PID
+			groups = append(groups, ProcessGroup{Key: key, Total: ProcessIO{PID: p.PID, Name: key, IOPrio: -1}})
+		}
+
+		g := &groups[i]
+		g.Members = append(g.Members, p)
+		g.Total.add(p)
+		if p.PID < g.Total.PID {
+			g.Total.PID = p.PID
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if sortReverse {
+			return lessProcess(groups[j].Total, groups[i].Total, currentSort)
+		}
+		return lessProcess(groups[i].Total, groups[j].Total, currentSort)
+	})
+	return groups
+}
+
+// selectedGroup is the key of the group row last selected in a grouped view
+var selectedGroup string
+
+// groupThroughput sums the I/O rates of the processes in one group and of
+// all processes, so the group can be shown as a share of the system total.
+func groupThroughput(processes []ProcessIO, by GroupBy, key string) (group ProcessIO, total float64, found bool) {
+	for _, p := range processes {
+		total += p.ReadRate + p.WriteRate
+		if groupKey(p, by) != key {
+			continue
+		}
+		found = true
+		group.ReadRate += p.ReadRate
+		group.WriteRate += p.WriteRate
+	}
+	return group, total, found
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// logSizeThreshold is the size in bytes past which a continuously growing
+// open file is reported as a runaway log. Zero disables the check.
+var logSizeThreshold int64
+
+// growthSamples is how many consecutive samples a file must grow in before
+// it counts as continuously appended to.
+const growthSamples = 3
+
+type fileGrowth struct {
+	Size    int64
+	Growing int
+	// Rate is how fast the file grew since the previous sample, in bytes
+	// per second
+	Rate  float64
+	Taken time.Time
+	seen  bool
+}
+
+var fileSizes = make(map[string]*fileGrowth)
+
+type runawayFile struct {
+	PID  int32
+	Name string
+	Path string
+	Size int64
+}
+
+// runawayFiles holds the files flagged by the latest sample, largest first.
+var runawayFiles []runawayFile
+
+func trackFileGrowth(pid int32, name, path string) {
+	g, ok := fileSizes[path]
+	if ok && g.seen {
+		// Already checked this sample through another process
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+	now := time.Now()
+	if !ok {
+		g = &fileGrowth{Size: info.Size()}
+		fileSizes[path] = g
+	} else if info.Size() > g.Size {
+		g.Growing++
+		g.Rate = float64(info.Size()-g.Size) / now.Sub(g.Taken).Seconds()
+	} else {
+		g.Growing = 0
+		g.Rate = 0
+	}
+	g.Size = info.Size()
+	g.Taken = now
+	g.seen = true
+
+	if logSizeThreshold > 0 && g.Growing >= growthSamples && g.Size >= logSizeThreshold {
+		runawayFiles = append(runawayFiles, runawayFile{PID: pid, Name: name, Path: path, Size: g.Size})
+	}
+}
+
+// startFileGrowth must be called before a sample's trackFileGrowth calls.
+func startFileGrowth() {
+	runawayFiles = runawayFiles[:0]
+}
+
+// finishFileGrowth forgets files that are no longer open.
+func finishFileGrowth() {
+	for path, g := range fileSizes {
+		if !g.seen {
+			delete(fileSizes, path)
+			continue
+		}
+		g.seen = false
+	}
+	sort.Slice(runawayFiles, func(i, j int) bool {
+		return runawayFiles[i].Size > runawayFiles[j].Size
+	})
+}
+
+func (f runawayFile) String() string {
+	return fmt.Sprintf("%s is %s and still growing (PID %d %s)", f.Path, humanizeBytes(float64(f.Size)), f.PID, f.Name)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const headerHeight = 3
+
+// headerMinWidths lists the known header elements and the narrowest width
+// each can be drawn at before wrapping to the next header row.
+var headerMinWidths = map[string]int{
+	"cpu":        20,
+	"mem":        20,
+	"swap":       20,
+	"load":       26,
+	"totals":     34,
+	"clock":      12,
+	"hostname":   20,
+	"throughput": 36,
+	"disk":       44,
+}
+
+func parseHeaderLayout(s string) ([]string, error) {
+	var elements []string
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if _, ok := headerMinWidths[e]; !ok {
+			return nil, fmt.Errorf("unknown header element %q", e)
+		}
+		if slices.Contains(elements, e) {
+			// Elements share one widget, which can only be drawn once
+			return nil, fmt.Errorf("header element %q is listed twice", e)
+		}
+		elements = append(elements, e)
+	}
+	return elements, nil
+}
+
+func textBox(title, text string) *widgets.Paragraph {
+	p := widgets.NewParagraph()
+	p.Title = title
+	p.Text = text
+	return p
+}
+
+func headerWidget(element string, cpuGauge, memGauge *widgets.Gauge, totals ioTotals) ui.Drawable {
+	switch element {
+	case "cpu":
+		return cpuGauge
+	case "mem":
+		return memGauge
+	case "swap":
+		g := widgets.NewGauge()
+		g.Title = "Swap Usage"
+		if swap, err := mem.SwapMemory(); err == nil {
+			g.Percent = int(swap.UsedPercent)
+		}
+		return g
+	case "load":
+		text := "-"
+		if avg, err := load.Avg(); err == nil {
+			text = fmt.Sprintf("%.2f %.2f %.2f", avg.Load1, avg.Load5, avg.Load15)
+		}
+		return textBox("Load", text)
+	case "totals":
+		return textBox("Total I/O", fmt.Sprintf("R %s/s  W %s/s", humanizeBytes(totals.Read), humanizeBytes(totals.Write)))
+	case "throughput":
+		// The text is trimmed to the newest samples once the width is known
+		return textBox(fmt.Sprintf("R %s/s  W %s/s (write history)", humanizeBytes(totals.Read), humanizeBytes(totals.Write)),
+			sparkline(totalWriteHistory.values()))
+	case "disk":
+		// Only the busiest device fits on one line
+		text := "-"
+		if len(diskStats) > 0 {
+			text = diskStats[0].String()
+		}
+		return textBox("Busiest Disk (util, queue)", text)
+	case "clock":
+		return textBox("Time", time.Now().Format("15:04:05"))
+	default:
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "-"
+		}
+		return textBox("Host", hostname)
+	}
+}
+
+// pinSummary replaces the header widgets with a single borderless line, so
+// scrolling a long table on a small terminal never drops the system context
+var pinSummary bool
+
+func summaryHeader(w int) ([]ui.Drawable, int) {
+	cpuGauge, memGauge, totals, _ := getSystemStats()
+	line := widgets.NewParagraph()
+	line.Border = false
+	line.Text = fmt.Sprintf("CPU %d%%  MEM %d%%  I/O R %s/s W %s/s", cpuGauge.Percent, memGauge.Percent,
+		humanizeBytes(totals.Read), humanizeBytes(totals.Write))
+	line.SetRect(0, 0, w, 1)
+	return []ui.Drawable{line}, 1
+}
+
+// buildHeader lays out the header elements left to right, wrapping onto
+// another row when the next element no longer fits, and stretching each row
+// to the full terminal width. It returns the widgets and the total height.
+func buildHeader(elements []string, w int) ([]ui.Drawable, int) {
+	if len(elements) == 0 {
+		return nil, 0
+	}
+	cpuGauge, memGauge, totals, _ := getSystemStats()
+
+	var rows [][]string
+	used := 0
+	for _, e := range elements {
+		if len(rows) == 0 || used+headerMinWidths[e] > w {
+			rows = append(rows, nil)
+			used = 0
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], e)
+		used += headerMinWidths[e]
+	}
+
+	var drawables []ui.Drawable
+	for i, row := range rows {
+		y := i * headerHeight
+		for j, e := range row {
+			widget := headerWidget(e, cpuGauge, memGauge, totals)
+			widget.SetRect(j*w/len(row), y, (j+1)*w/len(row), y+headerHeight)
+			if e == "throughput" {
+				box := widget.(*widgets.Paragraph)
+				spark := []rune(box.Text)
+				box.Text = string(spark[max(len(spark)-box.Inner.Dx(), 0):])
+			}
+			drawables = append(drawables, widget)
+		}
+	}
+	return drawables, len(rows) * headerHeight
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotFor(t *testing.T) {
+	start := time.Unix(1000, 0)
+	later := start.Add(time.Second)
+	id := procIdentity{CreateTime: 5000, StartTicks: 42}
+
+	tests := []struct {
+		name      string
+		identity  procIdentity
+		read      float64
+		write     float64
+		wantFresh bool
+	}{
+		{"matching identity", id, 300, 150, false},
+		{"different start ticks", procIdentity{CreateTime: 5000, StartTicks: 43}, 300, 150, true},
+		{"different create time", procIdentity{CreateTime: 6000, StartTicks: 42}, 300, 150, true},
+		{"unreadable start ticks", procIdentity{CreateTime: 5000}, 300, 150, false},
+		{"read counter went backwards", id, 50, 150, true},
+		{"write counter went backwards", id, 300, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots = make(map[int32]*procSnapshot)
+			first := snapshotFor(7, id, 100, 50, 0, 0, start)
+
+			snap := snapshotFor(7, tt.identity, tt.read, tt.write, 0, 0, later)
+			if fresh := snap != first; fresh != tt.wantFresh {
+				t.Fatalf("fresh snapshot = %v, want %v", fresh, tt.wantFresh)
+			}
+			if snapshots[7] != snap {
+				t.Fatalf("snapshot for pid 7 was not stored")
+			}
+
+			elapsed := later.Sub(snap.LastSample).Seconds()
+			if tt.wantFresh {
+				if elapsed != 0 || snap.LastRead != tt.read || snap.LastWrite != tt.write {
+					t.Errorf("fresh snapshot would report a non-zero rate: elapsed %v, last %v/%v", elapsed, snap.LastRead, snap.LastWrite)
+				}
+				return
+			}
+			if got, want := (tt.read-snap.LastRead)/elapsed, tt.read-100; got != want {
+				t.Errorf("read rate = %v, want %v", got, want)
+			}
+			if got, want := (tt.write-snap.LastWrite)/elapsed, tt.write-50; got != want {
+				t.Errorf("write rate = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// ioLimit returns the read and write bandwidth caps, in bytes per second,
+// that cgroup v2 io.max places on a process. Limits are summed over devices
+// and the tightest cgroup on the way up to the root wins. Zero means no cap.
+func ioLimit(pid int32) (read, write float64) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return 0, 0
+	}
+	var group string
+	for _, line := range strings.Split(string(data), "\n") {
+		// The unified hierarchy is the "0::" entry
+		if rest, ok := strings.CutPrefix(line, "0::"); ok {
+			group = rest
+			break
+		}
+	}
+	if group == "" {
+		return 0, 0
+	}
+
+	for dir := group; ; dir = path.Dir(dir) {
+		r, w := readIOMax(path.Join(cgroupRoot, dir, "io.max"))
+		if r > 0 && (read == 0 || r < read) {
+			read = r
+		}
+		if w > 0 && (write == 0 || w < write) {
+			write = w
+		}
+		if dir == "/" || dir == "." {
+			return read, write
+		}
+	}
+}
+
+func readIOMax(file string) (read, write float64) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, 0
+	}
+	// Each line looks like "8:0 rbps=1048576 wbps=max riops=max wiops=max"
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, field := range strings.Fields(line) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok || value == "max" {
+				continue
+			}
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbps":
+				read += n
+			case "wbps":
+				write += n
+			}
+		}
+	}
+	return read, write
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// ioLimit is only implemented on Linux, where cgroup v2 io.max exists.
+func ioLimit(pid int32) (read, write float64) {
+	return 0, 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// I/O scheduling classes as encoded by ioprio_get and ioprio_set.
+const (
+	ioprioClassNone = iota
+	ioprioClassRT
+	ioprioClassBE
+	ioprioClassIdle
+)
+
+const ioprioClassShift = 13
+
+var ioprioClassNames = map[int]string{ioprioClassRT: "rt", ioprioClassBE: "be", ioprioClassIdle: "idle"}
+
+// formatIOPrio renders a raw ioprio value the way iotop does.
+func formatIOPrio(prio int) string {
+	if prio < 0 {
+		return "-"
+	}
+	class, level := prio>>ioprioClassShift, prio&0xff
+	if class == ioprioClassIdle {
+		return "idle"
+	}
+	if name, ok := ioprioClassNames[class]; ok {
+		return fmt.Sprintf("%s/%d", name, level)
+	}
+	return "-"
+}
+
+// parseIOPrio reads a class and level typed as "be/4", "rt/0" or "idle".
+func parseIOPrio(s string) (int, error) {
+	name, levelText, hasLevel := strings.Cut(strings.TrimSpace(s), "/")
+	for class, className := range ioprioClassNames {
+		if className != name {
+			continue
+		}
+		if class == ioprioClassIdle {
+			return class << ioprioClassShift, nil
+		}
+		level := 4
+		if hasLevel {
+			var err error
+			if level, err = strconv.Atoi(levelText); err != nil || level < 0 || level > 7 {
+				return 0, fmt.Errorf("invalid level %q: want 0 (highest) to 7", levelText)
+			}
+		}
+		return class<<ioprioClassShift | level, nil
+	}
+	return 0, fmt.Errorf("invalid I/O priority %q: want rt/N, be/N or idle", s)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "syscall"
+
+const ioprioWhoProcess = 1
+
+// ioPriority returns the ioprio value of a process, or -1. Processes
+// without an explicit class get best-effort at a level derived from their
+// nice value, which is what the kernel schedules them with.
+func ioPriority(pid int32) int {
+	prio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return -1
+	}
+	if int(prio)>>ioprioClassShift != ioprioClassNone {
+		return int(prio)
+	}
+	// The raw getpriority syscall returns 20 - nice
+	raw, err := syscall.Getpriority(syscall.PRIO_PROCESS, int(pid))
+	if err != nil {
+		return -1
+	}
+	nice := 20 - raw
+	return ioprioClassBE<<ioprioClassShift | (nice+20)/5
+}
+
+func setIOPriority(pid int32, prio int) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(prio)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// I/O priorities are a Linux scheduler feature.
+func ioPriority(pid int32) int {
+	return -1
+}
+
+func setIOPriority(pid int32, prio int) error {
+	return errors.ErrUnsupported
+}
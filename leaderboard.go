@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type leaderKey struct {
+	PID  int32
+	Name string
+}
+
+// leaderEntry accumulates the bytes a process has moved since go-iotop
+// started watching it. Entries outlive the process so past culprits stay
+// visible after they exit.
+type leaderEntry struct {
+	leaderKey
+	Read    float64
+	Write   float64
+	Running bool
+}
+
+var leaderboard = make(map[leaderKey]*leaderEntry)
+
+var leaderboardHeaders = []string{"PID", "Name", "Total Read", "Total Write", "Status"}
+
+var leaderboardWidths = []int{8, 30, 14, 14, 10}
+
+func recordLeaderboard(pid int32, name string, read, write float64) {
+	key := leaderKey{PID: pid, Name: name}
+	entry, ok := leaderboard[key]
+	if !ok {
+		entry = &leaderEntry{leaderKey: key}
+		leaderboard[key] = entry
+	}
+	entry.Read += read
+	entry.Write += write
+	entry.Running = true
+}
+
+// markLeaderboardExited flags entries whose process was not seen this sample.
+// Entries are matched on PID and name, so a reused PID doesn't keep an
+// exited process running. Exited processes that never did any I/O are
+// forgotten.
+func markLeaderboardExited(seen map[leaderKey]bool) {
+	for key, entry := range leaderboard {
+		if seen[key] {
+			continue
+		}
+		if entry.Read+entry.Write == 0 {
+			delete(leaderboard, key)
+			continue
+		}
+		entry.Running = false
+	}
+}
+
+func resetLeaderboard() {
+	for key, entry := range leaderboard {
+		if !entry.Running {
+			delete(leaderboard, key)
+			continue
+		}
+		entry.Read, entry.Write = 0, 0
+	}
+}
+
+func leaderboardRows(limit int) [][]string {
+	entries := make([]*leaderEntry, 0, len(leaderboard))
+	for _, e := range leaderboard {
+		if e.Read+e.Write > 0 {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Read+entries[i].Write > entries[j].Read+entries[j].Write
+	})
+
+	rows := [][]string{leaderboardHeaders}
+	for _, e := range entries[:min(limit, len(entries))] {
+		status := "running"
+		if !e.Running {
+			status = "exited"
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", e.PID),
+			e.Name,
+			humanizeBytes(e.Read),
+			humanizeBytes(e.Write),
+			status,
+		})
+	}
+	return rows
+}
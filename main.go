@@ -2,11 +2,23 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"image"
 	"log"
+	"math/rand"
+	"net"
+	"os"
+	"runtime/debug"
+	"runtime/pprof"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
@@ -15,28 +27,276 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 )
 
+type View int
+
+const (
+	ViewProcesses View = iota
+	ViewLeaderboard
+	ViewBaseline
+	ViewDirectories
+	ViewFiles
+	ViewDetail
+)
+
+var currentView View
+
 type SortBy int
 
 const (
 	SortByCPU SortBy = iota
 	SortByRead
 	SortByWrite
+	SortByPID
+	SortByName
+	SortByMem
+	SortByAvgRead
+	SortByAvgWrite
+	SortByPressure
 )
 
 var currentSort SortBy
 
+// sortReverse flips the natural order of the current sort key
+var sortReverse bool
+
+// ascending reports whether a sort key naturally orders smallest first.
+func ascending(by SortBy) bool {
+	return by == SortByPID || by == SortByName
+}
+
+func lessProcess(a, b ProcessIO, by SortBy) bool {
+	switch by {
+	case SortByRead:
+		if showAccumulated {
+			return a.AccumRead > b.AccumRead
+		}
+		return a.ReadRate > b.ReadRate
+	case SortByWrite:
+		if showAccumulated {
+			return a.AccumWrite > b.AccumWrite
+		}
+		return a.WriteRate > b.WriteRate
+	case SortByPID:
+		return a.PID < b.PID
+	case SortByName:
+		return a.Name < b.Name
+	case SortByMem:
+		return a.MemPercent > b.MemPercent
+	case SortByAvgRead:
+		return a.AvgRead > b.AvgRead
+	case SortByAvgWrite:
+		return a.AvgWrite > b.AvgWrite
+	case SortByPressure:
+		return a.Pressure > b.Pressure
+	default:
+		return a.CPUPercent > b.CPUPercent
+	}
+}
+
+func sortProcesses(processes []ProcessIO) {
+	sort.SliceStable(processes, func(i, j int) bool {
+		if sortReverse {
+			return lessProcess(processes[j], processes[i], currentSort)
+		}
+		return lessProcess(processes[i], processes[j], currentSort)
+	})
+}
+
+// headerRow labels the columns, marking the active sort column with its
+// direction.
+func headerRow() []string {
+	cols := visibleColumns()
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		row[i] = c.Header
+		if showAccumulated && c.AccumHeader != "" {
+			row[i] = c.AccumHeader
+		}
+		if c.Sort != currentSort {
+			continue
+		}
+		if ascending(c.Sort) != sortReverse {
+			row[i] += "▲"
+		} else {
+			row[i] += "▼"
+		}
+	}
+	return row
+}
+
+// columnAt returns the table column under screen column x, or -1.
+func columnAt(x, minX int, widths []int) int {
+	for col, width := range widths {
+		if x >= minX && x < minX+width {
+			return col
+		}
+		minX += width + 1
+	}
+	return -1
+}
+
+var showThreads bool
+
+var showAgeHistogram bool
+
+// showCmdline replaces the process name with its full command line
+var showCmdline bool
+
+// procIdentity tells a process apart from a later one reusing its PID.
+type procIdentity struct {
+	CreateTime int64
+	StartTicks uint64
+}
+
+// matches compares identities, ignoring fields either side couldn't read.
+func (id procIdentity) matches(other procIdentity) bool {
+	if id.CreateTime != 0 && other.CreateTime != 0 && id.CreateTime != other.CreateTime {
+		return false
+	}
+	if id.StartTicks != 0 && other.StartTicks != 0 && id.StartTicks != other.StartTicks {
+		return false
+	}
+	return true
+}
+
+// procSnapshot holds per-PID state that persists across refreshes.
+type procSnapshot struct {
+	Identity   procIdentity
+	FirstSeen  time.Time
+	FirstRead  float64
+	FirstWrite float64
+	LastRead   float64
+	LastWrite  float64
+	LastSample time.Time
+	ReadRates  *ring
+	WriteRates *ring
+	History    *ring
+	PeakRate   float64
+	Files      map[string]bool
+	LastFaults uint64
+	LastBlkio  uint64
+	ReadTrend  *ring
+	WriteTrend *ring
+	WaitTrend  *ring
+	// Open files from the last sample, when they were collected for every
+	// process, or as loaded on demand for the rows on screen
+	HaveFiles bool
+	OpenFiles []string
+	Paths     []string
+}
+
+// snapshotFor returns the snapshot to diff pid's counters against, starting
+// a fresh one when the PID was reused or its counters went backwards
+func snapshotFor(pid int32, identity procIdentity, read, write float64, faults, blkio uint64, now time.Time) *procSnapshot {
+	snap, ok := snapshots[pid]
+	if ok && snap.Identity.matches(identity) && read >= snap.LastRead && write >= snap.LastWrite {
+		return snap
+	}
+	snap = &procSnapshot{
+		Identity:   identity,
+		FirstSeen:  now,
+		FirstRead:  read,
+		FirstWrite: write,
+		LastRead:   read,
+		LastWrite:  write,
+		LastSample: now,
+		LastFaults: faults,
+		LastBlkio:  blkio,
+		ReadRates:  newRing(avgWindow),
+		WriteRates: newRing(avgWindow),
+		History:    newRing(historyLength),
+		ReadTrend:  newRing(trendLength),
+		WriteTrend: newRing(trendLength),
+	}
+	snapshots[pid] = snap
+	return snap
+}
+
+// showFileDelta reports which files the clicked process opened and closed
+// since the previous sample
+var showFileDelta bool
+
+// diffFiles compares paths with the open files of the previous sample and
+// remembers them for the next one. Nothing is reported the first time.
+func (s *procSnapshot) diffFiles(paths []string) (opened, closed []string) {
+	current := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		current[path] = true
+		if s.Files != nil && !s.Files[path] {
+			opened = append(opened, path)
+		}
+	}
+	for path := range s.Files {
+		if !current[path] {
+			closed = append(closed, path)
+		}
+	}
+	sort.Strings(closed)
+	s.Files = current
+	return opened, closed
+}
+
+// avgWindow is the number of samples each displayed rate is averaged over
+var avgWindow = 1
+
+var snapshots = make(map[int32]*procSnapshot)
+
+// resetSessionStats restarts all session-relative measurements from now.
+// Snapshots are kept so the latest counters remain available as a baseline.
+func resetSessionStats() {
+	now := time.Now()
+	for _, snap := range snapshots {
+		snap.FirstSeen = now
+		snap.FirstRead = snap.LastRead
+		snap.FirstWrite = snap.LastWrite
+		snap.PeakRate = 0
+	}
+	resetLeaderboard()
+	cpuAverage.reset()
+	memAverage.reset()
+}
+
+type ThreadInfo struct {
+	TID  int32
+	Name string
+}
+
 type ProcessIO struct {
-	PID         int32
-	Name        string
-	ReadBytes   float64
-	WriteBytes  float64
-	LastRead    float64
-	LastWrite   float64
+	PID        int32
+	PPID       int32
+	NSPID      int32
+	Name       string
+	Exe        string
+	Cmdline    string
+	Container  string
+	ReadBytes  float64
+	WriteBytes float64
+	// AccumRead and AccumWrite count bytes since the process was first
+	// seen or the session stats were last reset
+	AccumRead   float64
+	AccumWrite  float64
 	ReadRate    float64
 	WriteRate   float64
+	AvgRead     float64
+	AvgWrite    float64
+	History     []float64
+	PeakRate    float64
+	Changed     bool
+	OpenedFiles []string
+	ClosedFiles []string
 	OpenFiles   []string
-	CPUPercent  float64
-	MemPercent  float32
+	Paths       []string
+	// FaultRate is major page faults per second and IOWait the percentage
+	// of the last interval spent blocked on block I/O
+	FaultRate  float64
+	IOWait     float64
+	Pressure   float64
+	CPUPercent float64
+	MemPercent float32
+	Nice       int32
+	IOPrio     int
+	CreateTime int64
+	Threads    []ThreadInfo
 }
 
 func min(a, b int) int {
@@ -46,198 +306,1840 @@ func min(a, b int) int {
 	return b
 }
 
+// compactUnits makes humanizeBytes use single-character suffixes without a
+// space, e.g. "12.3M", for narrow terminals
+var compactUnits bool
+
 func humanizeBytes(bytes float64) string {
-	units := []string{"B", "KB", "MB", "GB", "TB"}
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	if compactUnits {
+		units = []string{"B", "K", "M", "G", "T", "P"}
+	}
 	unitIndex := 0
 	value := bytes
 
-	for value > 1024 && unitIndex < len(units)-1 {
+	for value >= 1024 && unitIndex < len(units)-1 {
 		value /= 1024
 		unitIndex++
 	}
 
+	if compactUnits {
+		return fmt.Sprintf("%.1f%s", value, units[unitIndex])
+	}
 	return fmt.Sprintf("%.2f %s", value, units[unitIndex])
 }
 
-func getSystemStats() (*widgets.Gauge, *widgets.Gauge, error) {
+// threadName reads the kernel thread name from /proc/<pid>/task/<tid>/comm,
+// falling back to the TID when it is unavailable.
+func threadName(pid, tid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/task/%d/comm", pid, tid))
+	if err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name
+		}
+	}
+	return strconv.Itoa(int(tid))
+}
+
+func getThreads(p *process.Process) []ThreadInfo {
+	threads, err := p.Threads()
+	if err != nil || len(threads) < 2 {
+		return nil
+	}
+
+	infos := make([]ThreadInfo, 0, len(threads))
+	for tid := range threads {
+		infos = append(infos, ThreadInfo{TID: tid, Name: threadName(p.Pid, tid)})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].TID < infos[j].TID
+	})
+	return infos
+}
+
+// ageHistogram summarizes how long processes have been running, which makes
+// fork storms (many very young processes) easy to spot.
+func ageHistogram(processes []ProcessIO, now time.Time) string {
+	buckets := []struct {
+		label string
+		limit time.Duration
+		count int
+	}{
+		{label: "<1m", limit: time.Minute},
+		{label: "<1h", limit: time.Hour},
+		{label: "<1d", limit: 24 * time.Hour},
+		{label: "older"},
+	}
+
+	for _, p := range processes {
+		if p.CreateTime == 0 {
+			continue
+		}
+		age := now.Sub(time.UnixMilli(p.CreateTime))
+		for i := range buckets {
+			if buckets[i].limit == 0 || age < buckets[i].limit {
+				buckets[i].count++
+				break
+			}
+		}
+	}
+
+	parts := make([]string, 0, len(buckets))
+	for _, b := range buckets {
+		parts = append(parts, fmt.Sprintf("%s: %d", b.label, b.count))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// cpuInterval is how often the system CPU gauge is resampled; between
+// samples the cached value is shown. Zero samples on every refresh.
+var cpuInterval time.Duration
+
+var (
+	lastCPUSample  time.Time
+	lastCPUPercent float64
+)
+
+// sessionAverage is a time-weighted average of a sampled value.
+type sessionAverage struct {
+	sum    float64
+	weight float64
+	last   time.Time
+}
+
+func (a *sessionAverage) add(v float64, now time.Time) {
+	if !a.last.IsZero() {
+		dt := now.Sub(a.last).Seconds()
+		a.sum += v * dt
+		a.weight += dt
+	}
+	a.last = now
+}
+
+func (a *sessionAverage) value(fallback float64) float64 {
+	if a.weight == 0 {
+		return fallback
+	}
+	return a.sum / a.weight
+}
+
+// reset restarts the average while keeping the time of the last sample.
+func (a *sessionAverage) reset() {
+	a.sum, a.weight = 0, 0
+}
+
+// showSystemAverages switches the gauges to session averages
+var showSystemAverages bool
+
+var cpuAverage, memAverage sessionAverage
+
+// ioTotals is the throughput of every sampled process combined.
+type ioTotals struct {
+	Read  float64
+	Write float64
+}
+
+// totalHistoryLength is how many samples of total write throughput the
+// header sparkline keeps
+const totalHistoryLength = 120
+
+var (
+	lastTotals        ioTotals
+	totalWriteHistory = newRing(totalHistoryLength)
+)
+
+// getSystemStats returns the CPU and memory gauges and the I/O totals of the
+// latest process sample.
+func getSystemStats() (*widgets.Gauge, *widgets.Gauge, ioTotals, error) {
+	if activeReplay != nil {
+		return activeReplay.stats()
+	}
+	now := time.Now()
 	cpuGauge := widgets.NewGauge()
 	cpuGauge.Title = "CPU Usage"
-	cpuPercent, err := cpu.Percent(0, false)
-	if err == nil && len(cpuPercent) > 0 {
-		cpuGauge.Percent = int(cpuPercent[0])
+	if now.Sub(lastCPUSample) >= cpuInterval {
+		cpuPercent, err := cpu.Percent(0, false)
+		if err == nil && len(cpuPercent) > 0 {
+			lastCPUPercent = cpuPercent[0]
+			lastCPUSample = now
+			cpuAverage.add(lastCPUPercent, now)
+		}
 	}
+	cpuGauge.Percent = int(lastCPUPercent)
 	
 	memGauge := widgets.NewGauge()
 	memGauge.Title = "Memory Usage"
 	memStats, err := mem.VirtualMemory()
 	if err == nil {
 		memGauge.Percent = int(memStats.UsedPercent)
+		memAverage.add(memStats.UsedPercent, now)
+	}
+
+	if showSystemAverages {
+		cpuGauge.Title = "CPU Usage (session avg)"
+		cpuGauge.Percent = int(cpuAverage.value(lastCPUPercent))
+		memGauge.Title = "Memory Usage (session avg)"
+		memGauge.Percent = int(memAverage.value(float64(memGauge.Percent)))
 	}
 
-	return cpuGauge, memGauge, err
+	return cpuGauge, memGauge, lastTotals, err
 }
 
 func getProcessesIO() ([]ProcessIO, error) {
-	processes, err := process.Processes()
+	pids, err := process.Pids()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
+	seen := make(map[int32]bool, len(pids))
+	seenLeaders := make(map[leaderKey]bool, len(pids))
+
+	if collectPIDs != nil {
+		kept := pids[:0]
+		for _, pid := range pids {
+			if collectPIDs[pid] {
+				kept = append(kept, pid)
+			}
+		}
+		pids = kept
+	}
+
+	resetSkips(len(pids))
+	startFileGrowth()
+
 	var processStats []ProcessIO
-	for _, p := range processes {
-		name, err := p.Name()
-		if err != nil {
+	for _, s := range collectSamples(pids) {
+		if s.skipErr != nil {
+			recordSkip(s.skipStage, s.skipErr)
 			continue
 		}
-
-		ioStats, err := p.IOCounters()
-		if err != nil {
+		if s.name == "" {
 			continue
 		}
+		pid, name := s.handle.proc.Pid, s.name
+		paths := s.paths
+		if logSizeThreshold > 0 || currentView == ViewFiles {
+			for _, path := range paths {
+				trackFileGrowth(pid, name, path)
+			}
+		}
+
+		currentRead := float64(s.read)
+		currentWrite := float64(s.write)
 
-		cpuPercent, _ := p.CPUPercent()
-		memPercent, _ := p.MemoryPercent()
+		seen[pid] = true
+		// Only diff against a snapshot of the same process: a reused PID, or
+		// counters going backwards, starts over from a fresh baseline
+		identity := procIdentity{CreateTime: s.createTime, StartTicks: s.handle.startTicks}
+		faults, blkio, havePressure := s.faults, s.blkio, s.havePressure
+		snap := snapshotFor(pid, identity, currentRead, currentWrite, faults, blkio, now)
+		recordLeaderboard(pid, name, currentRead-snap.LastRead, currentWrite-snap.LastWrite)
+		seenLeaders[leaderKey{PID: pid, Name: name}] = true
 
-		openFiles, _ := p.OpenFiles()
-		files := make([]string, 0)
-		for _, f := range openFiles {
-			if f.Path != "" {
-				files = append(files, f.Path)
+		var faultRate, ioWait float64
+		// Rates are the mean of the last avgWindow per-sample rates
+		if elapsed := now.Sub(snap.LastSample).Seconds(); elapsed > 0 {
+			sampleRead := (currentRead - snap.LastRead) / elapsed
+			sampleWrite := (currentWrite - snap.LastWrite) / elapsed
+			snap.ReadRates.push(sampleRead)
+			snap.WriteRates.push(sampleWrite)
+			snap.History.push(sampleRead + sampleWrite)
+			snap.ReadTrend.push(sampleRead)
+			snap.WriteTrend.push(sampleWrite)
+			snap.PeakRate = max(snap.PeakRate, sampleRead+sampleWrite)
+			if havePressure && faults >= snap.LastFaults && blkio >= snap.LastBlkio {
+				faultRate = float64(faults-snap.LastFaults) / elapsed
+				ioWait = float64(blkio-snap.LastBlkio) / clockTicks / elapsed * 100
+				if pid == waitTrendPID {
+					if snap.WaitTrend == nil {
+						snap.WaitTrend = newRing(trendLength)
+					}
+					snap.WaitTrend.push(ioWait)
+				}
 			}
 		}
+		readRate, writeRate := snap.ReadRates.mean(), snap.WriteRates.mean()
 
-		currentRead := float64(ioStats.ReadBytes)
-		currentWrite := float64(ioStats.WriteBytes)
-		
-		// Find previous stats to calculate rate
-		var readRate, writeRate float64
-		for _, prev := range processStats {
-			if prev.PID == p.Pid {
-				readRate = currentRead - prev.LastRead
-				writeRate = currentWrite - prev.LastWrite
-				break
-			}
+		var opened, closed []string
+		if showFileDelta {
+			opened, closed = snap.diffFiles(paths)
+		} else {
+			snap.Files = nil
+		}
+		changed := currentRead != snap.LastRead || currentWrite != snap.LastWrite
+		snap.LastRead = currentRead
+		snap.LastWrite = currentWrite
+		snap.LastSample = now
+		snap.LastFaults, snap.LastBlkio = faults, blkio
+
+		// Average rates over the whole time this PID has been watched
+		var avgRead, avgWrite float64
+		if elapsed := now.Sub(snap.FirstSeen).Seconds(); elapsed > 0 {
+			avgRead = (currentRead - snap.FirstRead) / elapsed
+			avgWrite = (currentWrite - snap.FirstWrite) / elapsed
 		}
 		
+		snap.HaveFiles, snap.OpenFiles, snap.Paths = s.filesLoaded, s.files, s.paths
 		processStats = append(processStats, ProcessIO{
-			PID:         p.Pid,
+			PID:         pid,
+			PPID:        s.ppid,
+			NSPID:       s.nspid,
 			Name:        name,
+			Exe:         s.exe,
+			Cmdline:     s.cmdline,
+			Container:   containerName(s.container),
 			ReadBytes:   currentRead,
 			WriteBytes:  currentWrite,
-			LastRead:    currentRead,
-			LastWrite:   currentWrite,
+			AccumRead:   currentRead - snap.FirstRead,
+			AccumWrite:  currentWrite - snap.FirstWrite,
 			ReadRate:    readRate,
 			WriteRate:   writeRate,
-			OpenFiles:   files,
-			CPUPercent:  cpuPercent,
-			MemPercent:  memPercent,
+			AvgRead:     avgRead,
+			AvgWrite:    avgWrite,
+			History:     snap.History.values(),
+			PeakRate:    snap.PeakRate,
+			Changed:     changed,
+			OpenedFiles: opened,
+			ClosedFiles: closed,
+			OpenFiles:   s.files,
+			Paths:       paths,
+			FaultRate:   faultRate,
+			IOWait:      ioWait,
+			CPUPercent:  s.cpuPercent,
+			MemPercent:  s.memPercent,
+			Nice:        s.nice,
+			IOPrio:      s.ioPrio,
+			CreateTime:  s.createTime,
+			Threads:     s.threads,
 		})
 	}
 
-	sort.Slice(processStats, func(i, j int) bool {
-		switch currentSort {
-		case SortByRead:
-			return processStats[i].ReadRate > processStats[j].ReadRate
-		case SortByWrite:
-			return processStats[i].WriteRate > processStats[j].WriteRate
-		default:
-			return processStats[i].CPUPercent > processStats[j].CPUPercent
+	for pid := range snapshots {
+		if !seen[pid] {
+			delete(snapshots, pid)
 		}
-	})
+	}
+	markLeaderboardExited(seenLeaders)
+	finishFileGrowth()
+
+	// Totals are taken before children are folded into their parents, which
+	// would count the same bytes twice
+	lastTotals = ioTotals{}
+	for _, p := range processStats {
+		lastTotals.Read += p.ReadRate
+		lastTotals.Write += p.WriteRate
+	}
+	totalWriteHistory.push(lastTotals.Write)
+	totalReadHistory.push(lastTotals.Read)
+
+	// The tree rolls descendants up itself
+	if sumChildren && !treeMode {
+		addChildrenIO(processStats)
+	}
+	for i := range processStats {
+		processStats[i].Pressure = pressure(processStats[i])
+	}
+	exportOTLP(processStats)
+	publishMetrics(processStats)
+	sortProcesses(processStats)
+	recordSample(processStats, now)
+	logSummary(processStats, now)
 
 	return processStats, nil
 }
 
+// startProfile writes a CPU profile to path for at most d. The returned
+// function stops and flushes the profile early and is safe to call twice.
+func startProfile(path string, d time.Duration) (func(), error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	time.AfterFunc(d, stop)
+	return stop, nil
+}
+
+// Below these dimensions the layout can't be drawn meaningfully
+const (
+	minTermWidth   = 40
+	minTermHeight  = 10
+	minTableHeight = 5
+)
+
+func renderTooSmall(w, h int) {
+	ui.Clear()
+	msg := widgets.NewParagraph()
+	msg.Border = false
+	msg.Text = fmt.Sprintf("Terminal too small (%dx%d)\nResize to at least %dx%d", w, h, minTermWidth, minTermHeight)
+	msg.SetRect(0, 0, max(w, 1), max(h, 1))
+	ui.Render(msg)
+}
+
+// truncateRunes shortens s to at most width runes, ending with an ellipsis
+// when anything was cut.
+func truncateRunes(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width || width < 1 {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// showNamespaces tags processes living in another PID namespace with the
+// PID they have there
+var showNamespaces bool
+
+func displayName(p ProcessIO) string {
+	name := p.Name
+	if showCmdline && p.Cmdline != "" {
+		name = p.Cmdline
+	}
+	if p.NSPID != 0 {
+		name = fmt.Sprintf("[ns:%d] %s", p.NSPID, name)
+	}
+	return name
+}
+
+// ioLabel describes what the I/O columns measure.
+func ioLabel() string {
+	label := fmt.Sprintf("%s, sampled every %v", currentAccounting.Label(), refreshInterval)
+	if sumChildren && !treeMode {
+		label += ", I/O includes all children"
+	}
+	return label
+}
+
+// refreshInterval is how often processes are sampled. Rates are always
+// divided by the time actually elapsed, which drifts from this with jitter
+// and scheduling delays.
+var refreshInterval = time.Second
+
+// minRefreshInterval keeps sampling cheap enough that reading /proc for
+// every process doesn't dominate the CPU usage it is trying to show
+const minRefreshInterval = 100 * time.Millisecond
+
+// percentPrecision is the number of decimals shown for percentages
+var percentPrecision = 1
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'f', percentPrecision, 64)
+}
+
+func processRow(p ProcessIO) []string {
+	cols := visibleColumns()
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		row[i] = c.Cell(p)
+	}
+	return row
+}
+
+// showAccumulated switches the Read/s and Write/s columns to the bytes moved
+// since go-iotop started watching, or since the last R reset
+var showAccumulated bool
+
+// showPeakShare adds a column comparing each process's current rate with the
+// highest rate it reached this session
+var showPeakShare bool
+
+func peakShare(p ProcessIO) string {
+	if p.PeakRate == 0 {
+		return "-"
+	}
+	return formatPercent((p.ReadRate+p.WriteRate)/p.PeakRate*100) + "%"
+}
+
+const (
+	defaultNameWidth = 30
+	maxNameWidth     = 80
+	minFilesWidth    = 10
+)
+
+// growNameColumn lets the Name column widen to fit the visible names
+var growNameColumn bool
+
+// nameWidth picks the Name column width for the given rows, where the
+// names are in column col.
+func nameWidth(rows [][]string, col int) int {
+	if !growNameColumn {
+		return defaultNameWidth
+	}
+	width := defaultNameWidth
+	for _, row := range rows {
+		width = max(width, len([]rune(row[col])))
+	}
+	return min(width, maxNameWidth)
+}
+
+// columnWidths lays out cols for an inner width of total, with an unsized
+// Name column name wide. The Open Files column takes whatever space the
+// others leave.
+func columnWidths(cols []column, name, total int) []int {
+	widths := make([]int, len(cols))
+	used := 0
+	nameCol, filesCol := -1, -1
+	for i, c := range cols {
+		switch {
+		case c.Width > 0:
+			widths[i] = c.Width
+		case c.Key == "name":
+			widths[i] = name
+			nameCol = i
+		default:
+			filesCol = i
+			continue
+		}
+		used += widths[i] + 1 // each column is followed by a separator
+	}
+	if filesCol < 0 {
+		return widths
+	}
+	if rest := total - used; rest < minFilesWidth && nameCol >= 0 && name > defaultNameWidth {
+		// Give the name back what the files column needs, but never shrink
+		// it below its default
+		widths[nameCol] = max(defaultNameWidth, name-(minFilesWidth-rest))
+		used -= name - widths[nameCol]
+	}
+	widths[filesCol] = max(total-used, 0)
+	return widths
+}
+
+// capUsage describes how close a rate is to a bandwidth cap
+func capUsage(rate, limit float64) string {
+	if limit <= 0 {
+		return "uncapped"
+	}
+	return fmt.Sprintf("%s%% of %s/s cap", formatPercent(rate/limit*100), humanizeBytes(limit))
+}
+
+func joinOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
+
+// maxRows caps the rows shown per page below what the terminal fits
+var maxRows int
+
+// clampOffset keeps a scroll offset within a list of total entries shown
+// page at a time. An offset past the end wraps to the top when wrap is set,
+// as auto-scroll does, and otherwise stops at the last full page.
+func clampOffset(offset, total, page int, wrap bool) int {
+	if wrap && offset >= total {
+		return 0
+	}
+	return max(min(offset, total-page), 0)
+}
+
+// editLine applies a key press to the text of a prompt.
+func editLine(text, key string) string {
+	switch key {
+	case "<Backspace>", "<C-<Backspace>>":
+		if r := []rune(text); len(r) > 0 {
+			return string(r[:len(r)-1])
+		}
+	case "<Space>":
+		return text + " "
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			return text + key
+		}
+	}
+	return text
+}
+
+func findProcess(processes []ProcessIO, pid int32) (ProcessIO, bool) {
+	for _, p := range processes {
+		if p.PID == pid {
+			return p, true
+		}
+	}
+	return ProcessIO{}, false
+}
+
+// matchesSelector reports whether p is the process a -select-on-start
+// value names, by PID when it is a number and by name otherwise.
+func matchesSelector(p ProcessIO, selector string) bool {
+	if pid, err := strconv.Atoi(selector); err == nil {
+		return int(p.PID) == pid
+	}
+	return p.Name == selector
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run is the body of main. Returning the exit code instead of calling
+// os.Exit lets every deferred cleanup, such as flushing the -profile or
+// printing the -strict summary, finish first.
+func run() (exitCode int) {
+	autoScroll := flag.Duration("autoscroll", 0, "page through the full process list at this cadence (0 disables)")
+	autoScrollIdle := flag.Duration("autoscroll-idle", 30*time.Second, "resume auto-scroll after this long without a keypress")
+	selectOnStart := flag.String("select-on-start", "", "start with the cursor on the first process with this PID or name, waiting for it to appear if needed")
+	follow := flag.Bool("follow", false, "keep the selected process or group on screen as the sort order moves it (toggle with l)")
+	groupFlag := flag.String("group", "none", "group processes by none, name, exe or container")
+	flag.DurationVar(&refreshInterval, "interval", time.Second, fmt.Sprintf("how often to sample processes (at least %v)", minRefreshInterval))
+	flag.DurationVar(&refreshInterval, "delay", time.Second, "alias for -interval")
+	intervalJitter := flag.Duration("interval-jitter", 0, "add a random delay of up to this much to each refresh")
+	useSyslog := flag.Bool("syslog", false, "periodically log per-process I/O summaries to the system log")
+	flag.DurationVar(&syslogInterval, "syslog-interval", time.Minute, "how often to write syslog summaries")
+	syslogSeverity := flag.String("syslog-severity", "info", "syslog severity: debug, info, notice, warning or err")
+	flag.IntVar(&syslogTop, "syslog-top", 10, "number of processes included in each syslog summary")
+	profilePath := flag.String("profile", "", "write a CPU profile of go-iotop itself to this file")
+	profileDuration := flag.Duration("profile-duration", 30*time.Second, "how long to collect the -profile CPU profile")
+	headerFlag := flag.String("header", "cpu,mem,throughput", "comma-separated header elements: cpu, mem, swap, load, totals, throughput, disk, clock, hostname")
+	accountingFlag := flag.String("accounting", "block", "I/O counters to show: block (disk I/O) or vfs (syscalls, includes cache hits)")
+	flag.BoolVar(&elevatedOnly, "elevated", false, "only show processes running at a negative nice value")
+	flag.DurationVar(&cpuInterval, "cpu-interval", 0, "resample the system CPU gauge at most this often (0 means every refresh)")
+	flag.BoolVar(&strictMode, "strict", false, "report why processes were skipped instead of dropping them silently")
+	flag.Float64Var(&strictMaxSkip, "strict-max-skip", 0, "with -strict, exit non-zero when more than this fraction of processes is skipped (0 disables)")
+	flag.IntVar(&avgWindow, "avg-window", 1, "average each rate over the last N samples (1 shows the latest sample; the window spans N refresh intervals)")
+	flag.BoolVar(&changedOnly, "changed", false, "only show processes whose I/O byte counters changed since the last sample")
+	flag.BoolVar(&showSparklines, "sparklines", false, "show a trend column of each process's recent total I/O")
+	listBackends := flag.Bool("list-backends", false, "list the I/O accounting sources usable with -accounting and exit")
+	flag.BoolVar(&showPeakShare, "peak", false, "show each process's current rate as a percentage of its session peak")
+	flag.BoolVar(&sumChildren, "sum-children", false, "include the I/O of all descendants in each process's rates")
+	flag.BoolVar(&showNamespaces, "pidns", false, "mark processes in other PID namespaces (containers) with their namespace PID")
+	flag.BoolVar(&compactUnits, "compact-units", false, "use single-character size suffixes, e.g. 12.3M")
+	logThresholdMB := flag.Int64("log-threshold", 0, "report open files past this many MB that keep growing (0 disables)")
+	flag.Float64Var(&minCPU, "min-cpu", 0, "hide processes using less than this CPU percentage")
+	flag.BoolVar(&growNameColumn, "no-truncate-name", false, fmt.Sprintf("widen the Name column to fit the visible names, up to %d characters", maxNameWidth))
+	flag.StringVar(&baselinePath, "baseline-file", "", "save baselines to this file and load the last one at startup")
+	maxFPS := flag.Float64("max-fps", 0, "repaint the screen at most this many times per second (0 means every sample)")
+	flag.IntVar(&percentPrecision, "precision", 1, "decimal places for CPU%, MEM% and other percentages (0 for whole numbers)")
+	dumpFormat := flag.String("dump-format", "text", "format of the full process dump written by D: text or csv")
+	flag.IntVar(&dirDepth, "dir-depth", 2, "path components that name a directory in the V directory view")
+	flag.BoolVar(&delayFirstRender, "delay-first-render", false, "wait one full interval before the first frame so it already shows valid rates")
+	pidFlag := flag.String("pid", "", "only sample these comma-separated PIDs")
+	userFlag := flag.String("user", "", "only sample processes owned by these comma-separated users")
+	flag.StringVar(&collectName, "name", "", "only sample processes whose name contains this, ignoring case")
+	flag.BoolVar(&activeOnly, "o", false, "only show processes reading or writing in the current interval (toggle with o)")
+	flag.BoolVar(&showAccumulated, "accumulated", false, "show bytes moved since startup instead of per-second rates (toggle with a)")
+	flag.BoolVar(&showFiles, "show-files", false, "read open files for every process on each sample instead of only the ones on screen")
+	flag.IntVar(&collectWorkers, "workers", collectWorkers, "number of processes to read from /proc in parallel")
+	flag.BoolVar(&showContainers, "containers", false, "add a column naming the container each process runs in (toggle with I)")
+	flag.StringVar(&dockerSocket, "docker-socket", dockerSocket, "Docker API socket used to name containers")
+	flag.BoolVar(&treeMode, "tree", false, "show processes under their parents with subtree totals (toggle with t)")
+	flag.BoolVar(&showTrendPanel, "trends", false, "graph total read and write throughput above the table (toggle with y)")
+	flag.BoolVar(&showDiskPanel, "disks", false, "show per-device throughput, IOPS, utilization and queue depth (toggle with d)")
+	flag.BoolVar(&pinSummary, "pin-summary", false, "show a one-line CPU, memory and I/O summary in place of the header widgets (toggle with p)")
+	var batch bool
+	flag.BoolVar(&batch, "batch", false, "write the top processes to stdout every interval instead of starting the UI")
+	flag.BoolVar(&batch, "b", false, "shorthand for -batch")
+	batchFormat := flag.String("format", "json", "output format of -batch: "+strings.Join(batchFormats, ", "))
+	summary := flag.Bool("summary", false, "print one line per interval with the total I/O rates and the busiest process, in bytes per second, instead of starting the UI")
+	outputPath := flag.String("output", "", "with -batch or -summary, write to this file instead of stdout, replacing what it held")
+	var batchCount int
+	flag.IntVar(&batchCount, "count", 0, "with -batch or -summary, exit after this many samples (0 runs until interrupted)")
+	flag.IntVar(&batchCount, "iterations", 0, "alias for -count")
+	batchLimit := flag.Int("n", 0, "with -batch, include at most this many processes per sample (0 includes all)")
+	flag.StringVar(&metricsAddr, "listen", "", "serve Prometheus metrics on this address, e.g. :9100; runs without the UI unless -batch or -summary is given")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "push I/O metrics to this OTLP/HTTP metrics URL every sample, e.g. http://localhost:4318/v1/metrics")
+	flag.IntVar(&otlpTop, "otlp-top", 20, "export per-process metrics for at most this many of the busiest processes")
+	flag.IntVar(&maxRows, "max-rows", 0, "show at most this many rows per page (0 fills the terminal)")
+	flag.IntVar(&maxRows, "limit", 0, "alias for -max-rows")
+	columnsFlag := flag.String("columns", defaultColumns, "comma-separated table columns, each optionally key:width, from "+strings.Join(columnKeys(), ", "))
+	sortFlag := flag.String("sort", "cpu", "initial sort column")
+	recordPath := flag.String("record", "", "append every sample to this file as a line of JSON, for -replay")
+	replayPath := flag.String("replay", "", "play back a -record file in the UI instead of sampling live")
+	diffMode := flag.Bool("diff", false, "compare two -record files given as arguments, printing how the mean I/O rate of each process changed from the first to the second")
+	configPath := flag.String("config", defaultConfigPath(), "read option defaults and colors from this TOML file")
+	pressureFlag := flag.String("pressure-weights", "io=1,wait=1,faults=1", "weights of the pressure sort: io per MiB/s, wait per percent blocked on I/O, faults per major fault/s")
+	topFiles := flag.Bool("top-files", false, "start in the hottest files view (toggle with O)")
+	dumpDir := flag.String("dump-dir", ".", "directory the D key writes process dumps to")
+	flag.Parse()
+	if err := applyEnv(flag.CommandLine); err != nil {
+		log.Fatal(err)
+	}
+	if *configPath != "" {
+		if err := applyConfig(flag.CommandLine, *configPath, *configPath != defaultConfigPath()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *dumpFormat != "text" && *dumpFormat != "csv" {
+		log.Fatalf("invalid -dump-format %q: want text or csv", *dumpFormat)
+	}
+
+	if dirDepth < 1 {
+		log.Fatalf("invalid -dir-depth %d: must be at least 1", dirDepth)
+	}
+
+	if percentPrecision < 0 {
+		log.Fatalf("invalid -precision %d: must not be negative", percentPrecision)
+	}
+	logSizeThreshold = *logThresholdMB << 20
+	if *topFiles {
+		currentView = ViewFiles
+	}
+
+	if *listBackends {
+		listAccounting(os.Stdout)
+		return
+	}
+
+	if avgWindow < 1 {
+		log.Fatalf("invalid -avg-window %d: must be at least 1", avgWindow)
+	}
+
+	var err error
+	if currentGroup, err = parseGroupBy(*groupFlag); err != nil {
+		log.Fatal(err)
+	}
+	if tableColumns, err = parseColumns(*columnsFlag); err != nil {
+		log.Fatalf("invalid -columns: %v", err)
+	}
+	if currentSort, err = parseSort(*sortFlag); err != nil {
+		log.Fatal(err)
+	}
+
+	if currentAccounting, err = parseAccounting(*accountingFlag); err != nil {
+		log.Fatal(err)
+	}
+	if currentAccounting == AccountingVFS {
+		// Fail early rather than showing an empty table
+		if _, _, err := vfsCounters(int32(os.Getpid())); err != nil {
+			log.Fatalf("-accounting vfs: %v", err)
+		}
+	}
+
+	if baselinePath != "" {
+		if err := loadBaseline(baselinePath); err != nil {
+			log.Fatalf("failed to load baseline: %v", err)
+		}
+	}
+
+	headerLayout, err := parseHeaderLayout(*headerFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *useSyslog {
+		if sysLogger, err = openSyslog(*syslogSeverity); err != nil {
+			log.Fatalf("failed to open syslog: %v", err)
+		}
+	}
+
+	if !slices.Contains(batchFormats, *batchFormat) {
+		log.Fatalf("invalid -format %q: want %s", *batchFormat, strings.Join(batchFormats, ", "))
+	}
+
+	if batchCount < 0 || *batchLimit < 0 {
+		log.Fatalf("invalid -count %d or -n %d: must not be negative", batchCount, *batchLimit)
+	}
+
+	if err := parseCollectPIDs(*pidFlag); err != nil {
+		log.Fatal(err)
+	}
+	parseCollectUsers(*userFlag)
+
+	if maxRows < 0 {
+		log.Fatalf("invalid -max-rows %d: must not be negative", maxRows)
+	}
+
+	if err := parsePressureWeights(*pressureFlag); err != nil {
+		log.Fatal(err)
+	}
+
+	if refreshInterval < minRefreshInterval {
+		log.Fatalf("invalid -interval %v: must be at least %v", refreshInterval, minRefreshInterval)
+	}
+
+	if otlpTop < 0 {
+		log.Fatalf("invalid -otlp-top %d: must not be negative", otlpTop)
+	}
+	if *intervalJitter < 0 {
+		log.Fatalf("invalid -interval-jitter %v: must not be negative", *intervalJitter)
+	}
+	if syslogInterval <= 0 {
+		log.Fatalf("invalid -syslog-interval %v: must be positive", syslogInterval)
+	}
+	if syslogTop < 0 {
+		log.Fatalf("invalid -syslog-top %d: must not be negative", syslogTop)
+	}
+	if *replayPath != "" && (*recordPath != "" || batch || *summary || metricsAddr != "") {
+		log.Fatal("-replay can't be combined with -record, -batch, -summary or -listen")
+	}
+	if *outputPath != "" && !batch && !*summary {
+		log.Fatal("-output needs -batch or -summary")
+	}
+	if *diffMode && flag.NArg() != 2 {
+		log.Fatal("-diff needs two -record files, e.g. -diff before.ndjson after.ndjson")
+	}
+
+	// Validation is done. From here on failures return instead of calling
+	// log.Fatal, so the defers registered along the way still run.
+	if *diffMode {
+		rows, err := diffSessions(flag.Arg(0), flag.Arg(1))
+		if err == nil {
+			err = writeDiff(os.Stdout, rows)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		return
+	}
+	if *replayPath != "" {
+		if activeReplay, err = openReplay(*replayPath); err != nil {
+			log.Print(err)
+			return 1
+		}
+	}
+	if *recordPath != "" {
+		if err := startRecording(*recordPath); err != nil {
+			log.Printf("failed to open -record file: %v", err)
+			return 1
+		}
+	}
+
+	output := os.Stdout
+	if *outputPath != "" {
+		f, err := createOutput(*outputPath)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Printf("failed to write -output file: %v", err)
+				exitCode = 1
+			}
+		}()
+		output = f
+	}
+
+	if otlpEndpoint != "" {
+		startOTLP()
+	}
+
+	if *profilePath != "" {
+		stopProfile, err := startProfile(*profilePath, *profileDuration)
+		if err != nil {
+			log.Printf("failed to start profile: %v", err)
+			return 1
+		}
+		defer stopProfile()
+	}
+
+	if strictMode {
+		// Printed once the terminal has been restored
+		defer func() {
+			fmt.Fprintln(os.Stderr, skipSummary())
+		}()
+	}
+
+	// Jitter desynchronizes instances sampling /proc on the same cadence
+	nextRefresh := func() time.Duration {
+		d := refreshInterval
+		if *intervalJitter > 0 {
+			d += time.Duration(rand.Int63n(int64(*intervalJitter)))
+		}
+		return d
+	}
+
+	const pageSize = 20
+
+	if metricsAddr != "" {
+		// Listening up front reports a busy port before sampling starts
+		ln, err := net.Listen("tcp", metricsAddr)
+		if err != nil {
+			log.Printf("failed to start metrics server: %v", err)
+			return 1
+		}
+		go func() {
+			log.Fatalf("metrics server stopped: %v", serveMetrics(ln))
+		}()
+		if !batch && !*summary {
+			if err := runExporter(nextRefresh); err != nil {
+				log.Print(err)
+				exitCode = 1
+			}
+			return
+		}
+	}
+
+	if *summary {
+		if warning := privilegeWarning(); warning != "" {
+			log.Print(warning)
+		}
+		if err := runSummary(output, nextRefresh, batchCount); err != nil {
+			log.Print(err)
+			exitCode = 1
+		}
+		return
+	}
+	if batch {
+		if warning := privilegeWarning(); warning != "" {
+			log.Print(warning)
+		}
+		if err := runBatch(output, nextRefresh, batchCount, *batchLimit, *batchFormat); err != nil {
+			log.Print(err)
+			exitCode = 1
+		}
+		return
+	}
+
 	if err := ui.Init(); err != nil {
-		log.Fatalf("failed to initialize termui: %v", err)
+		log.Printf("failed to initialize termui, falling back to plain output: %v", err)
+		if warning := privilegeWarning(); warning != "" {
+			log.Print(warning)
+		}
+		limit := pageSize
+		if maxRows > 0 {
+			limit = maxRows
+		}
+		if err := runPlain(nextRefresh, limit); err != nil {
+			log.Print(err)
+			exitCode = 1
+		}
+		return
 	}
-	defer ui.Close()
-	
-	currentSort = SortByCPU
+	// Restore the terminal before printing a panic, otherwise the trace
+	// lands on a raw-mode screen and the shell is left without a cursor.
+	// The panic becomes exit code 2 so the remaining defers still run.
+	defer func() {
+		r := recover()
+		ui.Close()
+		if r != nil {
+			fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", r, debug.Stack())
+			exitCode = 2
+		}
+	}()
 
 	table := widgets.NewTable()
-	table.TextStyle = ui.NewStyle(ui.ColorWhite)
+	table.TextStyle = ui.NewStyle(theme.Text)
 	table.RowSeparator = true
-	table.BorderStyle = ui.NewStyle(ui.ColorGreen)
+	table.BorderStyle = ui.NewStyle(theme.Border)
 	table.FillRow = true
 	table.Rows = make([][]string, 0)
-	table.RowStyles = make(map[int]ui.Style)
-	table.RowStyles[0] = ui.NewStyle(ui.ColorYellow, ui.ColorClear, ui.ModifierBold)
+	headerStyle := ui.NewStyle(theme.Header, ui.ColorClear, ui.ModifierBold)
+	// Elevated-priority processes doing I/O can starve everyone else
+	elevatedStyle := ui.NewStyle(theme.Elevated)
+	selectedStyle := ui.NewStyle(ui.ColorBlack, theme.Selected)
 
-	draw := func() {
-		w, h := ui.TerminalDimensions()
-		
-		cpuGauge, memGauge, _ := getSystemStats()
-		cpuGauge.SetRect(0, 0, w/2, 3)
-		memGauge.SetRect(w/2, 0, w, 3)
-		
-		table.SetRect(0, 3, w, h)
-		
+	pageOffset := 0
+	// pageRows is how many entries fit below the table header
+	pageRows := pageSize
+	var lastProcesses []ProcessIO
+	// rowPIDs maps table rows to the process drawn on them (0 for other rows)
+	var rowPIDs []int32
+	var rowGroups []string
+	var inspectPID int32
+	// selectedPID is the process highlighted with the arrow keys, or
+	// groupSelected is set when the highlight is on the selectedGroup row.
+	// selectedRow is where it was last drawn, so a vanished process hands
+	// the highlight to its neighbour.
+	var selectedPID int32
+	var groupSelected bool
+	var selectedRow int
+	selectRow := func(row int) {
+		selectedRow = row
+		groupSelected = rowGroups[row] != ""
+		selectedPID = rowPIDs[row]
+		if groupSelected {
+			selectedGroup = rowGroups[row]
+		} else if selectedPID != 0 {
+			inspectPID = selectedPID
+		}
+	}
+	// pendingSelect is the -select-on-start process not seen yet, and
+	// followSelected pages to the selection whenever sorting moves it
+	pendingSelect := *selectOnStart
+	followSelected := *follow
+	scrollTo := func(i int) {
+		if i < pageOffset || i >= pageOffset+pageRows {
+			pageOffset = i - i%pageRows
+		}
+	}
+	// track places the cursor for entry i of the list being paged: on p
+	// when -select-on-start names it, or back in view when follow mode is
+	// on and p is selected. It reports whether p was the one.
+	track := func(i int, p ProcessIO) bool {
+		switch {
+		case pendingSelect != "" && matchesSelector(p, pendingSelect):
+			selectedPID, inspectPID, groupSelected = p.PID, p.PID, false
+			pendingSelect = ""
+		case pendingSelect == "" && followSelected && !groupSelected && p.PID == selectedPID:
+		default:
+			return false
+		}
+		scrollTo(i)
+		return true
+	}
+	var baselineErr error
+	// notice is a one-line message about the outcome of the last action
+	var notice string
+	// filterInput holds the keys typed while filtering
+	var filterInput string
+	// historyPos is the filterHistory entry shown at the prompt, or
+	// len(filterHistory) for historyDraft, what was typed before Up
+	var historyPos int
+	var historyDraft string
+	if err := loadFilterHistory(); err != nil {
+		notice = fmt.Sprintf("Failed to load filter history: %v", err)
+	}
+	// prioritizing is set while the i prompt captures an I/O priority for
+	// prioPID into prioInput
+	var prioritizing bool
+	var prioPID int32
+	var prioInput string
+	// confirmSignal is set while asking which signal to send to signalPID.
+	// Enter sends SIGKILL when signalKill is set (K), SIGTERM otherwise (k).
+	var confirmSignal, signalKill bool
+	var signalPID int32
+	// frozen stops sampling so the screen holds still; annotating is set
+	// while the capture note prompt captures keys into noteInput
+	var frozen, annotating bool
+	var noteInput string
+	// banner stays above the table until dismissed with W
+	banner := privilegeWarning()
+
+	sample := func() {
+		if activeReplay != nil {
+			if err := activeReplay.step(1); err != nil {
+				notice = fmt.Sprintf("Replay failed: %v", err)
+			}
+			lastProcesses = activeReplay.processes()
+			lastTotals = activeReplay.frame.Totals
+			totalWriteHistory.push(lastTotals.Write)
+			totalReadHistory.push(lastTotals.Read)
+			return
+		}
 		processes, err := getProcessesIO()
 		if err != nil {
 			log.Printf("Error getting processes: %v", err)
 			return
 		}
+		lastProcesses = processes
+		sampleDisks()
+	}
+
+	var lastRender time.Time
+	render := func() {
+		lastRender = time.Now()
+		w, h := ui.TerminalDimensions()
+		processes := lastProcesses
+
+		var drawables []ui.Drawable
+		var tableTop int
+		if pinSummary {
+			drawables, tableTop = summaryHeader(w)
+		} else {
+			drawables, tableTop = buildHeader(headerLayout, w)
+		}
+		if w < minTermWidth || h < minTermHeight || h-tableTop < minTableHeight {
+			renderTooSmall(w, h)
+			return
+		}
+		if banner != "" && h-tableTop-3 >= minTableHeight {
+			warning := widgets.NewParagraph()
+			warning.Title = "Limited privileges (W to dismiss)"
+			warning.Text = banner
+			warning.TextStyle = ui.NewStyle(theme.Warning)
+			warning.BorderStyle = ui.NewStyle(theme.Warning)
+			warning.SetRect(0, tableTop, w, tableTop+3)
+			drawables = append(drawables, warning)
+			tableTop += 3
+		}
+		if rows := diskRows(maxDiskRows); showDiskPanel && h-tableTop-len(rows)-2 >= minTableHeight {
+			disks := widgets.NewTable()
+			disks.Title = "Disks"
+			disks.Rows = rows
+			disks.RowSeparator = false
+			disks.TextAlignment = ui.AlignLeft
+			disks.RowStyles = map[int]ui.Style{0: headerStyle}
+			disks.SetRect(0, tableTop, w, tableTop+len(rows)+2)
+			drawables = append(drawables, disks)
+			tableTop += len(rows) + 2
+		}
+		if showTrendPanel && h-tableTop-8 >= minTableHeight {
+			if plot := trendPlot("Total I/O", totalReadHistory, totalWriteHistory, image.Rect(0, tableTop, w, tableTop+8)); plot != nil {
+				drawables = append(drawables, plot)
+				tableTop += 8
+			}
+		}
+		if showAgeHistogram && h-tableTop-3 >= minTableHeight {
+			ages := widgets.NewParagraph()
+			ages.Title = "Process Ages"
+			ages.Text = ageHistogram(processes, time.Now())
+			ages.SetRect(0, tableTop, w, tableTop+3)
+			drawables = append(drawables, ages)
+			tableTop += 3
+		}
+		if group, total, ok := groupThroughput(processes, currentGroup, selectedGroup); currentGroup != GroupNone && ok && h-tableTop-3 >= minTableHeight {
+			gauge := widgets.NewGauge()
+			gauge.Title = "Group " + selectedGroup
+			if total > 0 {
+				gauge.Percent = int((group.ReadRate + group.WriteRate) / total * 100)
+			}
+			gauge.Label = fmt.Sprintf("%d%% of system I/O  R %s/s  W %s/s", gauge.Percent,
+				humanizeBytes(group.ReadRate), humanizeBytes(group.WriteRate))
+			gauge.SetRect(0, tableTop, w, tableTop+3)
+			drawables = append(drawables, gauge)
+			tableTop += 3
+		}
+		var statusLines []string
+		if inspected, ok := findProcess(processes, inspectPID); ok {
+			if showCmdline {
+				statusLines = append(statusLines, fmt.Sprintf("PID %d: %s", inspected.PID, displayName(inspected)))
+			}
+			if readCap, writeCap := ioLimit(inspected.PID); readCap > 0 || writeCap > 0 {
+				statusLines = append(statusLines, fmt.Sprintf("PID %d cgroup io.max: read %s, write %s", inspected.PID,
+					capUsage(inspected.ReadRate, readCap), capUsage(inspected.WriteRate, writeCap)))
+			}
+			if showFileDelta {
+				statusLines = append(statusLines,
+					fmt.Sprintf("PID %d opened: %s", inspected.PID, joinOrDash(inspected.OpenedFiles)),
+					fmt.Sprintf("PID %d closed: %s", inspected.PID, joinOrDash(inspected.ClosedFiles)))
+			}
+		}
+		if prioritizing {
+			statusLines = append(statusLines, fmt.Sprintf("I/O priority for PID %d: %s_  (rt/0-7, be/0-7 or idle; Enter to apply, Esc to cancel)", prioPID, prioInput))
+		}
+		if confirmSignal {
+			preselected := "SIGTERM"
+			if signalKill {
+				preselected = "SIGKILL"
+			}
+			statusLines = append(statusLines, fmt.Sprintf("Signal PID %d? Enter: %s, t: SIGTERM, 9: SIGKILL, any other key cancels", signalPID, preselected))
+		}
+		if annotating {
+			statusLines = append(statusLines, fmt.Sprintf("Note: %s_  (Enter to save the capture, Esc to cancel)", noteInput))
+		}
+		if filtering {
+			statusLines = append(statusLines, fmt.Sprintf("Filter: %s_  (Enter to apply, Esc to clear, Up/Down for history)", filterInput))
+		} else if nameFilter != "" {
+			statusLines = append(statusLines, fmt.Sprintf("Filter: %q  (/ to edit, Esc to clear)", nameFilter))
+		}
+		if notice != "" {
+			statusLines = append(statusLines, notice)
+		}
+		if err := otlpError(); err != nil {
+			statusLines = append(statusLines, fmt.Sprintf("OTLP export failed: %v", err))
+		}
+		if recordErr != nil {
+			statusLines = append(statusLines, fmt.Sprintf("Recording failed: %v", recordErr))
+		}
+		for _, f := range runawayFiles[:min(3, len(runawayFiles))] {
+			statusLines = append(statusLines, f.String())
+		}
+		tableBottom := h
+		if height := len(statusLines) + 2; len(statusLines) > 0 && h-tableTop-height >= minTableHeight {
+			status := widgets.NewParagraph()
+			status.Title = "Status"
+			status.Text = strings.Join(statusLines, "\n")
+			status.SetRect(0, h-height, w, h)
+			drawables = append(drawables, status)
+			tableBottom -= height
+		}
+		table.SetRect(0, tableTop, w, tableBottom)
+		drawables = append(drawables, table)
+		// Body rows are two lines apart because of the row separators
+		pageRows = max((table.Inner.Dy()-1)/2, 1)
+		if maxRows > 0 {
+			pageRows = min(pageRows, maxRows)
+		}
+		table.Title = ioLabel()
+		if strictMode {
+			table.Title += fmt.Sprintf(" | %d skipped", skippedCount())
+		}
+		if activeReplay != nil {
+			table.Title += " | " + activeReplay.status()
+		}
+		if frozen {
+			table.Title += " | FROZEN (f to resume)"
+		}
+		table.RowStyles = map[int]ui.Style{0: headerStyle}
 
-		rows := [][]string{{"PID", "Name", "CPU%", "MEM%", "Read/s", "Write/s", "Open Files"}}
-		maxProcesses := len(processes)
-		if maxProcesses > 20 {
-			maxProcesses = 20
+		if currentView == ViewLeaderboard {
+			table.Title = "Hall of Fame (session totals)"
+			table.ColumnWidths = leaderboardWidths
+			table.Rows = leaderboardRows(pageRows)
+			ui.Render(drawables...)
+			return
+		}
+		if currentView == ViewBaseline && currentBaseline != nil {
+			table.Title = fmt.Sprintf("I/O since %s (%s)", currentBaseline.Name, time.Since(currentBaseline.Taken).Round(time.Second))
+			if baselineErr != nil {
+				table.Title += fmt.Sprintf(" | save failed: %v", baselineErr)
+			}
+			table.ColumnWidths = baselineWidths
+			table.Rows = baselineRows(processes, pageRows)
+			ui.Render(drawables...)
+			return
+		}
+		if currentView != ViewDetail {
+			traceWait(0)
+		}
+		if currentView == ViewDetail {
+			if p, ok := findProcess(processes, selectedPID); ok {
+				traceWait(p.PID)
+				detail := widgets.NewParagraph()
+				detail.Title = fmt.Sprintf("PID %d %s (Enter or Esc to close)", p.PID, p.Name)
+				loadOpenFiles(&p)
+				detail.Text = processDetail(p)
+				detail.SetRect(0, tableTop, w, tableBottom)
+				drawables[len(drawables)-1] = detail
+				if snap := snapshots[p.PID]; snap != nil && tableBottom-tableTop >= 20 {
+					// The bottom third graphs this process's history, with
+					// its I/O wait alongside once there is some to show
+					split := tableBottom - (tableBottom-tableTop)/3
+					historyRight := w
+					if plot := waitPlot(snap.WaitTrend, image.Rect(w/2, split, w, tableBottom)); plot != nil {
+						historyRight = w / 2
+						detail.SetRect(0, tableTop, w, split)
+						drawables = append(drawables, plot)
+					}
+					if plot := trendPlot("I/O history", snap.ReadTrend, snap.WriteTrend, image.Rect(0, split, historyRight, tableBottom)); plot != nil {
+						detail.SetRect(0, tableTop, w, split)
+						drawables = append(drawables, plot)
+					}
+				}
+				ui.Render(drawables...)
+				return
+			}
+			// The process exited, fall back to the table
+			currentView = ViewProcesses
+		}
+		if currentView == ViewFiles {
+			table.Title = "Hottest files (by processes holding them open, then growth)"
+			table.ColumnWidths = topFilesWidths
+			table.Rows = topFilesRows(filterProcesses(processes), pageRows)
+			ui.Render(drawables...)
+			return
+		}
+		if currentView == ViewDirectories {
+			table.Title = "I/O by directory (approximate, based on open files)"
+			table.ColumnWidths = directoryWidths
+			table.Rows = directoryRows(filterProcesses(processes), pageRows)
+			ui.Render(drawables...)
+			return
 		}
 
-		table.ColumnWidths = []int{8, 30, 8, 8, 12, 12, 0} // Adjust column widths, last column takes remaining space
-		
-		for _, p := range processes[:maxProcesses] {
-			rows = append(rows, []string{
-				fmt.Sprintf("%d", p.PID),
-				p.Name,
-				fmt.Sprintf("%.1f", p.CPUPercent),
-				fmt.Sprintf("%.1f", p.MemPercent),
-				humanizeBytes(p.ReadRate),
-				humanizeBytes(p.WriteRate),
-				func() string {
-					if len(p.OpenFiles) == 0 {
-						return "-"
-					}
-					files := p.OpenFiles
-					if len(files) > 3 {
-						files = files[:3]
-					}
-					return strings.Join(files, "\n")
-				}(),
-			})
+		processes = filterProcesses(processes)
+		cols := visibleColumns()
+		pidCol, nameCol, filesCol := columnIndex(cols, "pid"), columnIndex(cols, "name"), columnIndex(cols, "files")
+		rows := [][]string{headerRow()}
+
+		rowPIDs = []int32{0}
+		rowGroups = []string{""}
+		appendProcess := func(p ProcessIO, indent string) {
+			if filesCol >= 0 {
+				loadOpenFiles(&p)
+			}
+			row := processRow(p)
+			if nameCol >= 0 {
+				row[nameCol] = indent + row[nameCol]
+			}
+			rows = append(rows, row)
+			rowPIDs = append(rowPIDs, p.PID)
+			rowGroups = append(rowGroups, "")
+			if p.Nice < 0 {
+				table.RowStyles[len(rows)-1] = elevatedStyle
+			}
+			for _, t := range p.Threads {
+				name := t.Name
+				if tid := strconv.Itoa(int(t.TID)); name != tid {
+					name = fmt.Sprintf("%s (%s)", name, tid)
+				}
+				row := make([]string, len(rows[0]))
+				if nameCol >= 0 {
+					row[nameCol] = indent + "  " + name
+				}
+				rows = append(rows, row)
+				rowPIDs = append(rowPIDs, 0)
+				rowGroups = append(rowGroups, "")
+			}
+		}
+
+		if treeMode {
+			tree := processTree(processes)
+			if pendingSelect != "" || followSelected {
+				for i, r := range tree {
+					if track(i, r.Total) {
+						break
+					}
+				}
+			}
+			pageOffset = clampOffset(pageOffset, len(tree), pageRows, *autoScroll > 0)
+			visible := tree[pageOffset:min(pageOffset+pageRows, len(tree))]
+			table.Title += " | Tree (Space expands or collapses)"
+			if *autoScroll > 0 || len(tree) > pageRows {
+				table.Title += fmt.Sprintf(" | Rows %d-%d of %d", pageOffset+1, pageOffset+len(visible), len(tree))
+			}
+			for _, r := range visible {
+				appendProcess(r.Total, r.indent())
+			}
+		} else if currentGroup == GroupNone {
+			if pendingSelect != "" || followSelected {
+				for i, p := range processes {
+					if track(i, p) {
+						break
+					}
+				}
+			}
+			pageOffset = clampOffset(pageOffset, len(processes), pageRows, *autoScroll > 0)
+			visible := processes[pageOffset:min(pageOffset+pageRows, len(processes))]
+			if *autoScroll > 0 || len(processes) > pageRows {
+				table.Title += fmt.Sprintf(" | Processes %d-%d of %d", pageOffset+1, pageOffset+len(visible), len(processes))
+			}
+			for _, p := range visible {
+				appendProcess(p, "")
+			}
+		} else {
+			groups := groupProcesses(processes, currentGroup)
+			if pendingSelect != "" || followSelected {
+			groupLoop:
+				for i, g := range groups {
+					if pendingSelect == "" && groupSelected && g.Key == selectedGroup {
+						scrollTo(i)
+						break
+					}
+					for _, p := range g.Members {
+						if track(i, p) {
+							if !expandGroups {
+								// Collapsed members have no row, so the
+								// cursor goes on their group
+								groupSelected, selectedGroup, selectedPID = true, g.Key, 0
+							}
+							break groupLoop
+						}
+					}
+				}
+			}
+			pageOffset = clampOffset(pageOffset, len(groups), pageRows, *autoScroll > 0)
+			visible := groups[pageOffset:min(pageOffset+pageRows, len(groups))]
+			if *autoScroll > 0 || len(groups) > pageRows {
+				table.Title += fmt.Sprintf(" | Groups %d-%d of %d", pageOffset+1, pageOffset+len(visible), len(groups))
+			}
+			for _, g := range visible {
+				row := processRow(g.Total)
+				if pidCol >= 0 {
+					row[pidCol] = fmt.Sprintf("(%d)", len(g.Members))
+				}
+				if filesCol >= 0 {
+					row[filesCol] = ""
+				}
+				rows = append(rows, row)
+				rowPIDs = append(rowPIDs, 0)
+				rowGroups = append(rowGroups, g.Key)
+				if expandGroups {
+					for _, p := range g.Members {
+						appendProcess(p, "  ")
+					}
+				}
+			}
+		}
+		if selectedPID != 0 || groupSelected {
+			row := 0
+			for i, pid := range rowPIDs {
+				if groupSelected && rowGroups[i] == selectedGroup || !groupSelected && pid == selectedPID {
+					row = i
+				}
+			}
+			if row == 0 {
+				row = nearestRow(rowPIDs, rowGroups, selectedRow)
+			}
+			selectRow(row)
+			if row != 0 {
+				table.RowStyles[row] = selectedStyle
+			}
+		}
+		name := defaultNameWidth
+		if nameCol >= 0 {
+			name = nameWidth(rows[1:], nameCol)
+		}
+		table.ColumnWidths = columnWidths(cols, name, table.Inner.Dx())
+		if nameCol >= 0 {
+			for _, row := range rows[1:] {
+				row[nameCol] = truncateRunes(row[nameCol], table.ColumnWidths[nameCol])
+			}
 		}
 		table.Rows = rows
 
-		ui.Render(cpuGauge, memGauge, table)
+		ui.Render(drawables...)
+	}
+
+	// redraw repaints after input without sampling, so a burst of key
+	// presses or resizes doesn't squeeze extra samples into the rate window.
+	// Settings that change what is collected show up on the next sample.
+	redraw := func() {
+		sortProcesses(lastProcesses)
+		render()
 	}
 
-	draw()
+	sample()
+	if !delayFirstRender {
+		render()
+	}
 
 	uiEvents := ui.PollEvents()
-	ticker := time.NewTicker(time.Second).C
+	refresh := time.NewTimer(nextRefresh())
+
+	var scrollTicker <-chan time.Time
+	if *autoScroll > 0 {
+		scrollTicker = time.NewTicker(*autoScroll).C
+	}
+	lastInput := time.Time{}
+
+	var minRenderGap time.Duration
+	if *maxFPS > 0 {
+		minRenderGap = time.Duration(float64(time.Second) / *maxFPS)
+	}
+	var pendingRender <-chan time.Time
 
 	for {
 		select {
 		case e := <-uiEvents:
+			if e.Type == ui.KeyboardEvent {
+				lastInput = time.Now()
+			}
+			if confirmSignal && e.Type == ui.KeyboardEvent {
+				confirmSignal = false
+				p, ok := findProcess(lastProcesses, signalPID)
+				switch {
+				case e.ID == "<C-c>":
+					return
+				case e.ID != "t" && e.ID != "9" && e.ID != "<Enter>":
+					notice = "Signal cancelled"
+				case !ok:
+					notice = fmt.Sprintf("PID %d has exited", signalPID)
+				default:
+					notice = signalProcess(p.PID, p.Name, e.ID == "9" || e.ID == "<Enter>" && signalKill)
+				}
+				redraw()
+				continue
+			}
+			if annotating && e.Type == ui.KeyboardEvent {
+				switch e.ID {
+				case "<C-c>":
+					return
+				case "<Enter>":
+					loadAllOpenFiles(lastProcesses)
+					if path, err := writeCapture(lastProcesses, *dumpDir, noteInput); err != nil {
+						notice = fmt.Sprintf("Capture failed: %v", err)
+					} else {
+						notice = fmt.Sprintf("Wrote capture of %d processes to %s", len(lastProcesses), path)
+					}
+					annotating = false
+				case "<Escape>":
+					annotating = false
+				default:
+					noteInput = editLine(noteInput, e.ID)
+				}
+				redraw()
+				continue
+			}
+			if prioritizing && e.Type == ui.KeyboardEvent {
+				switch e.ID {
+				case "<C-c>":
+					return
+				case "<Enter>":
+					prioritizing = false
+					prio, err := parseIOPrio(prioInput)
+					if err == nil {
+						err = setIOPriority(prioPID, prio)
+					}
+					switch {
+					case errors.Is(err, errors.ErrUnsupported):
+						notice = "I/O priorities are not supported on this platform"
+					case errors.Is(err, os.ErrPermission):
+						notice = fmt.Sprintf("Setting the I/O priority of PID %d was denied: other users' processes and the realtime class need root", prioPID)
+					case err != nil:
+						notice = fmt.Sprintf("Setting the I/O priority of PID %d failed: %v", prioPID, err)
+					default:
+						notice = fmt.Sprintf("Set the I/O priority of PID %d to %s", prioPID, formatIOPrio(prio))
+					}
+				case "<Escape>":
+					prioritizing = false
+				default:
+					prioInput = editLine(prioInput, e.ID)
+				}
+				redraw()
+				continue
+			}
+			if filtering && e.Type == ui.KeyboardEvent {
+				switch e.ID {
+				case "<C-c>":
+					return
+				case "<Enter>":
+					filtering = false
+					if filterInput != "" {
+						if err := addFilterHistory(filterInput); err != nil {
+							notice = fmt.Sprintf("Failed to save filter history: %v", err)
+						}
+					}
+				case "<Escape>":
+					nameFilter, filterInput = "", ""
+					filtering = false
+					pageOffset = 0
+				case "<Up>", "<Down>":
+					// Walk the history like a shell, keeping what was typed
+					// to come back to below the newest entry
+					if historyPos == len(filterHistory) {
+						historyDraft = filterInput
+					}
+					if e.ID == "<Up>" {
+						historyPos = max(historyPos-1, 0)
+					} else {
+						historyPos = min(historyPos+1, len(filterHistory))
+					}
+					if historyPos < len(filterHistory) {
+						filterInput = filterHistory[historyPos]
+					} else {
+						filterInput = historyDraft
+					}
+					nameFilter = filterInput
+					pageOffset = 0
+				default:
+					// The table narrows down as the filter is typed
+					filterInput = editLine(filterInput, e.ID)
+					nameFilter = filterInput
+					pageOffset = 0
+				}
+				redraw()
+				continue
+			}
 			switch e.ID {
 			case "q", "<C-c>":
 				return
+			case "/":
+				filtering = true
+				filterInput = nameFilter
+				loadCmdlines(lastProcesses)
+				historyPos = len(filterHistory)
+				redraw()
+			case "<Escape>":
+				if currentView == ViewDetail {
+					currentView = ViewProcesses
+					redraw()
+				} else if nameFilter != "" {
+					nameFilter = ""
+					pageOffset = 0
+					redraw()
+				}
 			case "r":
 				currentSort = SortByRead
-				draw()
+				sortReverse = false
+				redraw()
 			case "w":
 				currentSort = SortByWrite
-				draw()
+				sortReverse = false
+				redraw()
 			case "c":
 				currentSort = SortByCPU
-				draw()
+				sortReverse = false
+				redraw()
+			case "s":
+				// Cycle through every sort key, including the ones that have
+				// no column to click
+				currentSort = (currentSort + 1) % (SortByPressure + 1)
+				sortReverse = false
+				redraw()
+			case "R":
+				resetSessionStats()
+				redraw()
+			case "g":
+				currentGroup = (currentGroup + 1) % (GroupByContainer + 1)
+				pageOffset = 0
+				redraw()
+			case "e":
+				expandGroups = !expandGroups
+				redraw()
+			case "L":
+				if currentView == ViewLeaderboard {
+					currentView = ViewProcesses
+				} else {
+					currentView = ViewLeaderboard
+				}
+				redraw()
+			case "N":
+				elevatedOnly = !elevatedOnly
+				pageOffset = 0
+				redraw()
+			case "C":
+				showCmdline = !showCmdline
+				redraw()
+			case "x":
+				changedOnly = !changedOnly
+				pageOffset = 0
+				redraw()
+			case "S":
+				showSparklines = !showSparklines
+				redraw()
+			case "P":
+				showPeakShare = !showPeakShare
+				redraw()
+			case "G":
+				showSystemAverages = !showSystemAverages
+				redraw()
+			case "b":
+				baselineErr = saveBaseline(lastProcesses)
+				currentView = ViewBaseline
+				redraw()
+			case "B":
+				if currentView == ViewBaseline {
+					currentView = ViewProcesses
+				} else if currentBaseline != nil {
+					currentView = ViewBaseline
+				}
+				redraw()
+			case "F":
+				showFileDelta = !showFileDelta
+				redraw()
+			case "D":
+				loadAllOpenFiles(lastProcesses)
+				if path, err := dumpProcesses(lastProcesses, *dumpDir, *dumpFormat); err != nil {
+					notice = fmt.Sprintf("Dump failed: %v", err)
+				} else {
+					notice = fmt.Sprintf("Wrote %d processes to %s", len(lastProcesses), path)
+				}
+				render()
+			case "p":
+				pinSummary = !pinSummary
+				redraw()
+			case "f":
+				frozen = !frozen
+				redraw()
+			case "A":
+				// Freeze first so the capture matches what is on screen
+				frozen = true
+				annotating = true
+				noteInput = ""
+				redraw()
+			case "W":
+				banner = ""
+				redraw()
+			case "O":
+				if currentView == ViewFiles {
+					currentView = ViewProcesses
+				} else {
+					currentView = ViewFiles
+				}
+				redraw()
+			case "v":
+				filesMode = (filesMode + 1) % filesModeCount
+				redraw()
+			case "o":
+				activeOnly = !activeOnly
+				pageOffset = 0
+				redraw()
+			case "a":
+				showAccumulated = !showAccumulated
+				redraw()
+			case "V":
+				if currentView == ViewDirectories {
+					currentView = ViewProcesses
+				} else {
+					currentView = ViewDirectories
+				}
+				redraw()
+			case "y":
+				showTrendPanel = !showTrendPanel
+				redraw()
+			case "d":
+				showDiskPanel = !showDiskPanel
+				redraw()
+			case "H":
+				showAgeHistogram = !showAgeHistogram
+				redraw()
+			case "T":
+				showThreads = !showThreads
+				redraw()
+			case "I":
+				showContainers = !showContainers
+				redraw()
+			case "t":
+				treeMode = !treeMode
+				pageOffset = 0
+				redraw()
+			case "<Space>":
+				if treeMode && hasChildren(lastProcesses, selectedPID) {
+					collapsed[selectedPID] = !collapsed[selectedPID]
+					redraw()
+				}
+			case "<Up>", "<Down>":
+				if currentView != ViewProcesses {
+					break
+				}
+				// Group rows are selectable too, showing the group's share
+				// of system I/O like a click does
+				row := nearestRow(rowPIDs, rowGroups, 1)
+				if selectedPID != 0 || groupSelected {
+					dir := 1
+					if e.ID == "<Up>" {
+						dir = -1
+					}
+					row = stepRow(rowPIDs, rowGroups, selectedRow, dir)
+				}
+				if row < len(rowPIDs) {
+					selectRow(row)
+				}
+				redraw()
+			case ",", ".", "[", "]", "{", "}":
+				if activeReplay == nil {
+					break
+				}
+				var err error
+				switch e.ID {
+				case ",", ".":
+					// Stepping pauses playback so the frame stays put
+					frozen = true
+					if e.ID == "," {
+						err = activeReplay.step(-1)
+					} else {
+						err = activeReplay.step(1)
+					}
+				case "[":
+					err = activeReplay.seekBy(-time.Minute)
+				case "]":
+					err = activeReplay.seekBy(time.Minute)
+				case "{":
+					err = activeReplay.seekBy(-10 * time.Minute)
+				case "}":
+					err = activeReplay.seekBy(10 * time.Minute)
+				}
+				if err != nil {
+					notice = fmt.Sprintf("Replay failed: %v", err)
+				}
+				lastProcesses = activeReplay.processes()
+				lastTotals = activeReplay.frame.Totals
+				redraw()
+			case "k", "K":
+				if activeReplay != nil {
+					notice = replayOnly
+				} else if _, ok := findProcess(lastProcesses, selectedPID); ok {
+					confirmSignal = true
+					signalKill = e.ID == "K"
+					signalPID = selectedPID
+				} else {
+					notice = "No process selected: use the arrow keys to pick one"
+				}
+				redraw()
+			case "i":
+				if activeReplay != nil {
+					notice = replayOnly
+				} else if p, ok := findProcess(lastProcesses, selectedPID); ok {
+					prioritizing = true
+					prioPID = p.PID
+					prioInput = formatIOPrio(p.IOPrio)
+				} else {
+					notice = "No process selected: use the arrow keys to pick one"
+				}
+				redraw()
+			case "<Enter>":
+				if currentView == ViewDetail {
+					currentView = ViewProcesses
+				} else if currentView == ViewProcesses && activeReplay != nil {
+					notice = replayOnly
+				} else if currentView == ViewProcesses && selectedPID != 0 {
+					currentView = ViewDetail
+				}
+				redraw()
+			case "<PageDown>", "<PageUp>", "<Home>", "<End>":
+				// Paging away from the selection ends follow mode
+				if followSelected {
+					followSelected = false
+					notice = "Follow mode off"
+				}
+				switch e.ID {
+				case "<PageDown>":
+					pageOffset += pageRows
+				case "<PageUp>":
+					pageOffset = max(pageOffset-pageRows, 0)
+				case "<Home>":
+					pageOffset = 0
+				case "<End>":
+					pageOffset = len(lastProcesses)
+				}
+				redraw()
+			case "l":
+				followSelected = !followSelected
+				if followSelected {
+					notice = "Following the selection"
+				} else {
+					notice = "Follow mode off"
+				}
+				redraw()
+			case "<MouseLeft>":
+				// Clicking a column header sorts by it, clicking again reverses
+				m := e.Payload.(ui.Mouse)
+				if currentView != ViewProcesses || m.Y < table.Inner.Min.Y {
+					break
+				}
+				if m.Y > table.Inner.Min.Y {
+					// Clicking a process row shows its full command line.
+					// Rows are two lines apart because of the separators.
+					offset := m.Y - table.Inner.Min.Y
+					row := offset / 2
+					if offset%2 != 0 || row >= len(rowPIDs) {
+						break
+					}
+					// Clicking a group row shows its share of system I/O
+					if selectableRow(rowPIDs, rowGroups, row) {
+						selectRow(row)
+						redraw()
+					}
+					break
+				}
+				col := columnAt(m.X, table.Inner.Min.X, table.ColumnWidths)
+				cols := visibleColumns()
+				if col < 0 || col >= len(cols) || cols[col].Sort == noSort {
+					break
+				}
+				if cols[col].Sort == currentSort {
+					sortReverse = !sortReverse
+				} else {
+					currentSort = cols[col].Sort
+					sortReverse = false
+				}
+				redraw()
 			case "<Resize>":
-				draw()
+				redraw()
+			}
+		case <-refresh.C:
+			if frozen {
+				refresh.Reset(nextRefresh())
+				break
+			}
+			// Samples keep their cadence, but repaints are coalesced down to
+			// the render cap when the terminal can't keep up
+			sample()
+			if since := time.Since(lastRender); minRenderGap <= 0 || since >= minRenderGap {
+				render()
+			} else if pendingRender == nil {
+				pendingRender = time.After(minRenderGap - since)
+			}
+			if checkSkipLimit() != nil {
+				// The -strict summary printed on exit says what was skipped
+				exitCode = 1
+				return
+			}
+			refresh.Reset(nextRefresh())
+		case <-pendingRender:
+			pendingRender = nil
+			render()
+		case <-scrollTicker:
+			if time.Since(lastInput) >= *autoScrollIdle {
+				pageOffset += pageRows
+				redraw()
 			}
-		case <-ticker:
-			draw()
 		}
 	}
 }
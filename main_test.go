@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestHumanizeBytes(t *testing.T) {
+	const k = 1024.0
+	tests := []struct {
+		bytes   float64
+		long    string
+		compact string
+	}{
+		{0, "0.00 B", "0.0B"},
+		{1023, "1023.00 B", "1023.0B"},
+		{k, "1.00 KB", "1.0K"},
+		{1.5 * k, "1.50 KB", "1.5K"},
+		{k*k - 1, "1024.00 KB", "1024.0K"},
+		{k * k, "1.00 MB", "1.0M"},
+		{k * k * k, "1.00 GB", "1.0G"},
+		{k * k * k * k, "1.00 TB", "1.0T"},
+		{k * k * k * k * k, "1.00 PB", "1.0P"},
+		{k * k * k * k * k * k, "1024.00 PB", "1024.0P"},
+	}
+	defer func(old bool) { compactUnits = old }(compactUnits)
+	for _, tt := range tests {
+		compactUnits = false
+		if got := humanizeBytes(tt.bytes); got != tt.long {
+			t.Errorf("humanizeBytes(%v) = %q, want %q", tt.bytes, got, tt.long)
+		}
+		compactUnits = true
+		if got := humanizeBytes(tt.bytes); got != tt.compact {
+			t.Errorf("compact humanizeBytes(%v) = %q, want %q", tt.bytes, got, tt.compact)
+		}
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		value     float64
+		precision int
+		want      string
+	}{
+		{0, 0, "0"},
+		{0, 1, "0.0"},
+		{0, 2, "0.00"},
+		{42.25, 0, "42"},
+		{42.25, 1, "42.2"},
+		{42.25, 2, "42.25"},
+		{99.4, 0, "99"},
+		{99.5, 0, "100"},
+		{99.94, 1, "99.9"},
+		{99.95, 1, "100.0"},
+		{99.95, 2, "99.95"},
+		{99.995, 2, "100.00"},
+		{100, 0, "100"},
+		{100, 2, "100.00"},
+	}
+	defer func(old int) { percentPrecision = old }(percentPrecision)
+	for _, tt := range tests {
+		percentPrecision = tt.precision
+		if got := formatPercent(tt.value); got != tt.want {
+			t.Errorf("formatPercent(%v) with -precision %d = %q, want %q", tt.value, tt.precision, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsAddr is the address the Prometheus endpoint listens on. Empty
+// disables it.
+var metricsAddr string
+
+var (
+	metricsMu      sync.Mutex
+	metricsSamples []ProcessIO
+)
+
+// publishMetrics makes a sample visible to scrapes. Scrapes never sample by
+// themselves, which would squeeze extra points into every rate window.
+func publishMetrics(processes []ProcessIO) {
+	if metricsAddr == "" {
+		return
+	}
+	metricsMu.Lock()
+	metricsSamples = append(metricsSamples[:0], processes...)
+	metricsMu.Unlock()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metricFamily struct {
+	name  string
+	kind  string
+	help  string
+	value func(ProcessIO) float64
+}
+
+var metricFamilies = []metricFamily{
+	{"go_iotop_process_read_bytes_per_second", "gauge", "Bytes read per second.", func(p ProcessIO) float64 { return p.ReadRate }},
+	{"go_iotop_process_write_bytes_per_second", "gauge", "Bytes written per second.", func(p ProcessIO) float64 { return p.WriteRate }},
+	{"go_iotop_process_read_bytes_total", "counter", "Bytes read since the process started.", func(p ProcessIO) float64 { return p.ReadBytes }},
+	{"go_iotop_process_write_bytes_total", "counter", "Bytes written since the process started.", func(p ProcessIO) float64 { return p.WriteBytes }},
+	{"go_iotop_process_cpu_percent", "gauge", "CPU usage in percent.", func(p ProcessIO) float64 { return p.CPUPercent }},
+	{"go_iotop_process_memory_percent", "gauge", "Resident memory in percent of total.", func(p ProcessIO) float64 { return float64(p.MemPercent) }},
+}
+
+// writeMetrics renders processes in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, processes []ProcessIO) error {
+	labels := make([]string, len(processes))
+	for i, p := range processes {
+		labels[i] = fmt.Sprintf(`{pid="%d",name="%s",cmdline="%s"}`, p.PID, labelEscaper.Replace(p.Name), labelEscaper.Replace(p.Cmdline))
+	}
+
+	var b strings.Builder
+	for _, m := range metricFamilies {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, p := range processes {
+			fmt.Fprintf(&b, "%s%s %s\n", m.name, labels[i], strconv.FormatFloat(m.value(p), 'g', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// serveMetrics serves /metrics on ln until the listener fails.
+func serveMetrics(ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		metricsMu.Lock()
+		processes := append([]ProcessIO(nil), metricsSamples...)
+		metricsMu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, processes)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return server.Serve(ln)
+}
+
+// runExporter samples every interval with no output of its own, for running
+// the exporter as a service. It only returns when a sample skips more
+// processes than -strict-max-skip allows.
+func runExporter(nextRefresh func() time.Duration) error {
+	for {
+		if _, err := getProcessesIO(); err != nil {
+			log.Printf("Error getting processes: %v", err)
+		}
+		if err := checkSkipLimit(); err != nil {
+			return err
+		}
+		time.Sleep(nextRefresh())
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// showFiles reads open files for every process on each sample. Otherwise
+// they are only read for the processes on screen, since listing file
+// descriptors is the most expensive part of a sample.
+var showFiles bool
+
+// needAllFiles reports whether this sample needs every process's open files.
+// Recordings keep them so the files views work in a replay.
+func needAllFiles() bool {
+	return showFiles || recordFile != nil || showFileDelta || logSizeThreshold > 0 || currentView == ViewFiles || currentView == ViewDirectories
+}
+
+// readOpenFiles returns p's open files as shown in the table, with direct
+// I/O marked, and as plain paths.
+func readOpenFiles(p *process.Process) (files, paths []string) {
+	openFiles, _ := p.OpenFiles()
+	files = make([]string, 0)
+	paths = make([]string, 0, len(openFiles))
+	for _, f := range openFiles {
+		if f.Path == "" {
+			continue
+		}
+		paths = append(paths, f.Path)
+		if isDirectIO(p.Pid, f.Fd) {
+			// Direct I/O bypasses the page cache
+			files = append(files, "[D] "+f.Path)
+		} else {
+			files = append(files, f.Path)
+		}
+	}
+	return files, paths
+}
+
+// loadOpenFiles fills in p's open files if the last sample skipped them,
+// reading them at most once per sample.
+func loadOpenFiles(p *ProcessIO) {
+	snap, ok := snapshots[p.PID]
+	if !ok {
+		return
+	}
+	if !snap.HaveFiles {
+		proc := &process.Process{Pid: p.PID}
+		if h, ok := handles[p.PID]; ok {
+			proc = h.proc
+		}
+		snap.OpenFiles, snap.Paths = readOpenFiles(proc)
+		snap.HaveFiles = true
+	}
+	p.OpenFiles, p.Paths = snap.OpenFiles, snap.Paths
+}
+
+func loadAllOpenFiles(processes []ProcessIO) {
+	for i := range processes {
+		loadOpenFiles(&processes[i])
+	}
+}
+
+// FilesMode selects what the Open Files column shows.
+type FilesMode int
+
+const (
+	FilesPaths FilesMode = iota
+	FilesCount
+	FilesTypes
+	filesModeCount
+)
+
+var filesMode FilesMode
+
+// fileTypeOrder fixes the order of the types summary so cells don't
+// reshuffle between samples.
+var fileTypeOrder = []string{"reg", "dev", "sock", "pipe", "anon", "other"}
+
+// fileType classifies an open file by the target of its /proc fd link.
+func fileType(path string) string {
+	switch {
+	case strings.HasPrefix(path, "socket:"):
+		return "sock"
+	case strings.HasPrefix(path, "pipe:"):
+		return "pipe"
+	case strings.HasPrefix(path, "anon_inode:"):
+		return "anon"
+	case strings.HasPrefix(path, "/dev/"):
+		return "dev"
+	case strings.HasPrefix(path, "/"):
+		return "reg"
+	}
+	return "other"
+}
+
+func filesCell(p ProcessIO) string {
+	if len(p.OpenFiles) == 0 {
+		return "-"
+	}
+	switch filesMode {
+	case FilesCount:
+		return fmt.Sprintf("%d open", len(p.OpenFiles))
+	case FilesTypes:
+		counts := make(map[string]int)
+		for _, path := range p.Paths {
+			counts[fileType(path)]++
+		}
+		parts := make([]string, 0, len(counts))
+		for _, t := range fileTypeOrder {
+			if counts[t] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[t], t))
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	files := p.OpenFiles
+	if len(files) > 3 {
+		files = files[:3]
+	}
+	return strings.Join(files, "\n")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// otlpEndpoint is the OTLP/HTTP metrics URL samples are pushed to, e.g.
+// http://localhost:4318/v1/metrics. Empty disables the exporter.
+var otlpEndpoint string
+
+// otlpTop caps how many processes get their own series, keeping the
+// exported cardinality bounded on busy hosts
+var otlpTop = 20
+
+var (
+	otlpQueue   chan []ProcessIO
+	otlpMu      sync.Mutex
+	otlpLastErr error
+)
+
+// The types below are the subset of the OTLP JSON encoding needed for
+// gauges, which spares the binary the OpenTelemetry SDK and its gRPC and
+// protobuf dependencies. 64-bit integers are strings in the protobuf JSON
+// mapping. otlp_test.go checks the payload against the OTLP protos.
+type otlpValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpDataPoint struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsDouble     float64         `json:"asDouble"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpMetric struct {
+	Name  string `json:"name"`
+	Unit  string `json:"unit"`
+	Gauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+}
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
+
+// startOTLP starts the goroutine that pushes samples, so a slow collector
+// never holds up sampling. Samples arriving while a push is in flight are
+// dropped.
+func startOTLP() {
+	otlpQueue = make(chan []ProcessIO, 1)
+	client := &http.Client{Timeout: 5 * time.Second}
+	go func() {
+		for processes := range otlpQueue {
+			err := pushOTLP(client, processes, time.Now())
+			otlpMu.Lock()
+			otlpLastErr = err
+			otlpMu.Unlock()
+		}
+	}()
+}
+
+func exportOTLP(processes []ProcessIO) {
+	if otlpQueue == nil {
+		return
+	}
+	select {
+	case otlpQueue <- append([]ProcessIO(nil), processes...):
+	default:
+	}
+}
+
+// otlpError returns the outcome of the latest push.
+func otlpError() error {
+	otlpMu.Lock()
+	defer otlpMu.Unlock()
+	return otlpLastErr
+}
+
+// otlpPayload encodes a sample as an OTLP/JSON ExportMetricsServiceRequest.
+func otlpPayload(processes []ProcessIO, now time.Time) ([]byte, error) {
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	gauge := func(name, unit string) *otlpMetric {
+		return &otlpMetric{Name: name, Unit: unit}
+	}
+	point := func(m *otlpMetric, v float64, attrs ...otlpAttribute) {
+		m.Gauge.DataPoints = append(m.Gauge.DataPoints, otlpDataPoint{TimeUnixNano: ts, AsDouble: v, Attributes: attrs})
+	}
+
+	var read, write float64
+	for _, p := range processes {
+		read += p.ReadRate
+		write += p.WriteRate
+	}
+	systemRead := gauge("system.io.read_rate", "By/s")
+	systemWrite := gauge("system.io.write_rate", "By/s")
+	point(systemRead, read)
+	point(systemWrite, write)
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processes[i].ReadRate+processes[i].WriteRate > processes[j].ReadRate+processes[j].WriteRate
+	})
+	processRead := gauge("process.io.read_rate", "By/s")
+	processWrite := gauge("process.io.write_rate", "By/s")
+	for _, p := range processes[:min(otlpTop, len(processes))] {
+		attrs := []otlpAttribute{
+			{Key: "process.pid", Value: otlpValue{IntValue: strconv.Itoa(int(p.PID))}},
+			stringAttr("process.executable.name", p.Name),
+		}
+		point(processRead, p.ReadRate, attrs...)
+		point(processWrite, p.WriteRate, attrs...)
+	}
+
+	hostname, _ := os.Hostname()
+	body := map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{stringAttr("service.name", "go-iotop"), stringAttr("host.name", hostname)},
+			},
+			"scopeMetrics": []any{map[string]any{
+				"scope":   map[string]string{"name": "go-iotop"},
+				"metrics": []*otlpMetric{systemRead, systemWrite, processRead, processWrite},
+			}},
+		}},
+	}
+	return json.Marshal(body)
+}
+
+// otlpRetries is how many times a push is retried after a connection error
+// or one of the responses the OTLP/HTTP spec marks as retryable. otlpBackoff
+// is the first wait, doubled on each retry unless the collector sends
+// Retry-After.
+var (
+	otlpRetries = 3
+	otlpBackoff = time.Second
+)
+
+func otlpRetryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func pushOTLP(client *http.Client, processes []ProcessIO, now time.Time) error {
+	data, err := otlpPayload(processes, now)
+	if err != nil {
+		return err
+	}
+	backoff := otlpBackoff
+	for attempt := 0; ; attempt++ {
+		resp, postErr := client.Post(otlpEndpoint, "application/json", bytes.NewReader(data))
+		if postErr == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 == 2 {
+				return nil
+			}
+			err = fmt.Errorf("%s: %s", otlpEndpoint, resp.Status)
+			if !otlpRetryable(resp.StatusCode) {
+				return err
+			}
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+				backoff = time.Duration(seconds) * time.Second
+			}
+		} else {
+			err = postErr
+		}
+		if attempt == otlpRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
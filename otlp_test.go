@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestOTLPPayloadMatchesSchema(t *testing.T) {
+	now := time.Unix(1700000000, 5)
+	processes := []ProcessIO{
+		{PID: 10, Name: "postgres", ReadRate: 100, WriteRate: 50},
+		{PID: 20, Name: "rsync", ReadRate: 1000, WriteRate: 0},
+	}
+	data, err := otlpPayload(processes, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// protojson rejects unknown fields and mistyped values, so this fails
+	// if the hand-written encoding drifts from the OTLP protos
+	var req metricspb.MetricsData
+	if err := protojson.Unmarshal(data, &req); err != nil {
+		t.Fatalf("payload doesn't decode as OTLP metrics: %v\n%s", err, data)
+	}
+
+	if len(req.ResourceMetrics) != 1 {
+		t.Fatalf("got %d resource metrics, want 1", len(req.ResourceMetrics))
+	}
+	rm := req.ResourceMetrics[0]
+	resource := make(map[string]string)
+	for _, a := range rm.Resource.Attributes {
+		resource[a.Key] = a.Value.GetStringValue()
+	}
+	if resource["service.name"] != "go-iotop" {
+		t.Errorf("service.name = %q, want go-iotop", resource["service.name"])
+	}
+	if len(rm.ScopeMetrics) != 1 || rm.ScopeMetrics[0].Scope.Name != "go-iotop" {
+		t.Fatalf("want one go-iotop scope, got %v", rm.ScopeMetrics)
+	}
+
+	metrics := make(map[string]*metricspb.Metric)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	if got := metrics["system.io.read_rate"].GetGauge().DataPoints[0].GetAsDouble(); got != 1100 {
+		t.Errorf("system.io.read_rate = %v, want 1100", got)
+	}
+	points := metrics["process.io.read_rate"].GetGauge().GetDataPoints()
+	if len(points) != 2 {
+		t.Fatalf("got %d process.io.read_rate points, want 2", len(points))
+	}
+	// The busiest process comes first
+	p := points[0]
+	if p.TimeUnixNano != uint64(now.UnixNano()) || p.GetAsDouble() != 1000 {
+		t.Errorf("first point = %v at %d, want 1000 at %d", p.GetAsDouble(), p.TimeUnixNano, now.UnixNano())
+	}
+	for _, a := range p.Attributes {
+		switch a.Key {
+		case "process.pid":
+			if a.Value.GetIntValue() != 20 {
+				t.Errorf("process.pid = %v, want 20", a.Value)
+			}
+		case "process.executable.name":
+			if a.Value.GetStringValue() != "rsync" {
+				t.Errorf("process.executable.name = %v, want rsync", a.Value)
+			}
+		}
+	}
+}
+
+func TestPushOTLPRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		wantHits int
+	}{
+		{"accepted", []int{http.StatusOK}, false, 1},
+		{"retried after 503", []int{http.StatusServiceUnavailable, http.StatusOK}, false, 2},
+		{"gives up", []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}, true, 4},
+		{"not retryable", []int{http.StatusBadRequest, http.StatusOK}, true, 1},
+	}
+	defer func(endpoint string, backoff time.Duration) {
+		otlpEndpoint, otlpBackoff = endpoint, backoff
+	}(otlpEndpoint, otlpBackoff)
+	otlpBackoff = time.Millisecond
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+				w.WriteHeader(tt.statuses[min(hits, len(tt.statuses)-1)])
+				hits++
+			}))
+			defer server.Close()
+			otlpEndpoint = server.URL
+
+			err := pushOTLP(server.Client(), []ProcessIO{{PID: 1, Name: "init"}}, time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("%d requests, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
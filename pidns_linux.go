@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var selfPIDNamespace, _ = os.Readlink("/proc/self/ns/pid")
+
+// namespacePID returns the PID a process has inside its own PID namespace
+// when that namespace differs from ours, e.g. for processes in containers.
+// It returns 0 when the process shares our namespace or can't be resolved.
+func namespacePID(pid int32) int32 {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil || selfPIDNamespace == "" || ns == selfPIDNamespace {
+		return 0
+	}
+
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "NSpid:")
+		if !ok {
+			continue
+		}
+		// NSpid lists the PID in each nested namespace, innermost last
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return 0
+		}
+		nspid, err := strconv.ParseInt(fields[len(fields)-1], 10, 32)
+		if err != nil {
+			return 0
+		}
+		return int32(nspid)
+	}
+	return 0
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// namespacePID is only implemented on Linux.
+func namespacePID(pid int32) int32 {
+	return 0
+}
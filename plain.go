@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// delayFirstRender skips the frame drawn from the very first sample, whose
+// rates are all zero because there is nothing to diff against yet.
+var delayFirstRender bool
+
+// runPlain is the fallback used when termui cannot take over the terminal.
+// It redraws a plain text table with ANSI clear-screen codes on every
+// refresh until the process is interrupted, or until a sample skips more
+// processes than -strict-max-skip allows.
+func runPlain(nextRefresh func() time.Duration, limit int) error {
+	for first := true; ; first = false {
+		processes, err := getProcessesIO()
+		if err != nil {
+			log.Printf("Error getting processes: %v", err)
+		} else if !first || !delayFirstRender {
+			renderPlain(processes, limit)
+		}
+		if err := checkSkipLimit(); err != nil {
+			return err
+		}
+		time.Sleep(nextRefresh())
+	}
+}
+
+func renderPlain(processes []ProcessIO, limit int) {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+
+	cpuGauge, memGauge, totals, _ := getSystemStats()
+	fmt.Fprintf(&b, "CPU %d%%  MEM %d%%  I/O R %s/s W %s/s  (%s)\n\n", cpuGauge.Percent, memGauge.Percent,
+		humanizeBytes(totals.Read), humanizeBytes(totals.Write), ioLabel())
+
+	processes = filterProcesses(processes)
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headerRow(), "\t"))
+	processes = processes[:min(limit, len(processes))]
+	filesCol := columnIndex(visibleColumns(), "files")
+	if filesCol >= 0 {
+		loadAllOpenFiles(processes)
+	}
+	for _, p := range processes {
+		row := processRow(p)
+		if filesCol >= 0 {
+			row[filesCol] = strings.ReplaceAll(row[filesCol], "\n", ", ")
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+
+	os.Stdout.WriteString(b.String())
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// clockTicks is USER_HZ, the unit of the delay accounting counters
+const clockTicks = 100
+
+// pressureWeights scale the parts of the pressure score: io per MiB/s of
+// combined read and write, wait per percent of time blocked on I/O, and
+// faults per major page fault per second.
+var pressureWeights = map[string]float64{"io": 1, "wait": 1, "faults": 1}
+
+func parsePressureWeights(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if _, known := pressureWeights[key]; !ok || !known {
+			return fmt.Errorf("invalid pressure weight %q: want io=N, wait=N or faults=N", part)
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil || w < 0 {
+			return fmt.Errorf("invalid pressure weight %q: weights must be non-negative numbers", part)
+		}
+		pressureWeights[key] = w
+	}
+	return nil
+}
+
+// pressure ranks processes that both cause and suffer from I/O contention
+// above ones that merely move a lot of bytes.
+func pressure(p ProcessIO) float64 {
+	return pressureWeights["io"]*(p.ReadRate+p.WriteRate)/(1<<20) +
+		pressureWeights["wait"]*p.IOWait +
+		pressureWeights["faults"]*p.FaultRate
+}
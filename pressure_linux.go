@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pressureCounters returns a process's major page faults and the clock
+// ticks it has spent waiting for block I/O, fields 12 and 42 of
+// /proc/<pid>/stat. The wait is only counted with delay accounting enabled.
+func pressureCounters(pid int32) (majorFaults, blkioTicks uint64, ok bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return 0, 0, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 40 {
+		return 0, 0, false
+	}
+	majorFaults, err = strconv.ParseUint(fields[9], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	blkioTicks, err = strconv.ParseUint(fields[39], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return majorFaults, blkioTicks, true
+}
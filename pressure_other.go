@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// pressureCounters is only implemented on Linux, elsewhere pressure is
+// based on the I/O rate alone.
+func pressureCounters(pid int32) (majorFaults, blkioTicks uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// capSysPtrace is needed to read /proc/<pid>/io of other users' processes.
+const capSysPtrace = 19
+
+// privilegeWarning explains what will be missing when go-iotop runs without
+// the rights to read every process's I/O counters, or returns "".
+func privilegeWarning() string {
+	if os.Geteuid() == 0 || hasCapability(capSysPtrace) {
+		return ""
+	}
+	return "Not running as root: processes owned by other users are skipped and the totals undercount. Run as root or grant CAP_SYS_PTRACE for full accounting."
+}
+
+func hasCapability(bit uint) bool {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "CapEff:")
+		if !ok {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		return err == nil && caps&(1<<bit) != 0
+	}
+	return false
+}
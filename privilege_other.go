@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// privilegeWarning only knows about Unix user IDs outside Linux. Geteuid
+// returns -1 on Windows, where no warning is shown.
+func privilegeWarning() string {
+	if os.Geteuid() <= 0 {
+		return ""
+	}
+	return "Not running as root: processes owned by other users may be missing and the totals undercount."
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// recordFrame is one sample of a -record file, stored as a line of JSON.
+type recordFrame struct {
+	Time       time.Time   `json:"time"`
+	CPUPercent float64     `json:"cpu_percent"`
+	MemPercent float64     `json:"mem_percent"`
+	Totals     ioTotals    `json:"totals"`
+	Processes  []ProcessIO `json:"processes"`
+}
+
+var (
+	recordFile *os.File
+	recordErr  error
+	// recordCPUTimes is the recorder's own baseline, so recording doesn't
+	// shorten the interval the CPU gauge measures over
+	recordCPUTimes *cpu.TimesStat
+)
+
+// startRecording opens path for appending, so a restarted session adds to
+// the same file.
+func startRecording(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	recordFile = f
+	return nil
+}
+
+// recordSample appends a frame for processes. A failed write is shown in
+// the status line and retried with the next sample.
+func recordSample(processes []ProcessIO, now time.Time) {
+	if recordFile == nil {
+		return
+	}
+	frame := recordFrame{Time: now, CPUPercent: recordCPU(), Totals: lastTotals, Processes: processes}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		frame.MemPercent = vm.UsedPercent
+	}
+	line, err := json.Marshal(frame)
+	if err == nil {
+		// One write per frame keeps lines whole when the file is appended
+		// to by more than one session
+		_, err = recordFile.Write(append(line, '\n'))
+	}
+	recordErr = err
+}
+
+// recordCPU returns the system CPU usage since the previous frame.
+func recordCPU() float64 {
+	times, err := cpu.Times(false)
+	if err != nil || len(times) == 0 {
+		return 0
+	}
+	t := times[0]
+	last := recordCPUTimes
+	recordCPUTimes = &t
+	if last == nil {
+		return 0
+	}
+	total := func(c *cpu.TimesStat) float64 {
+		return c.User + c.System + c.Idle + c.Nice + c.Iowait + c.Irq + c.Softirq + c.Steal
+	}
+	elapsed := total(&t) - total(last)
+	if elapsed <= 0 {
+		return 0
+	}
+	idle := (t.Idle + t.Iowait) - (last.Idle + last.Iowait)
+	return math.Max(0, math.Min(100, (elapsed-idle)/elapsed*100))
+}
+
+// replay plays a -record file back. Only the offset and time of each frame
+// are kept in memory and frames are decoded as they are shown, so an
+// overnight recording can be scrubbed through without loading all of it.
+type replay struct {
+	f       *os.File
+	offsets []int64
+	times   []time.Time
+	pos     int
+	frame   recordFrame
+}
+
+// activeReplay replaces live sampling when -replay is given.
+var activeReplay *replay
+
+// replayOnly is the notice for keys that act on live processes.
+const replayOnly = "Not available in a replay: the recorded processes may be gone"
+
+func openReplay(path string) (*replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &replay{f: f, pos: -1}
+	br := bufio.NewReader(f)
+	var offset int64
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			var head struct {
+				Time time.Time `json:"time"`
+			}
+			if jerr := json.Unmarshal(line, &head); jerr == nil {
+				r.offsets = append(r.offsets, offset)
+				r.times = append(r.times, head.Time)
+			} else if err == nil {
+				// Only a last line cut short by an interrupted recording
+				// is skipped
+				f.Close()
+				return nil, fmt.Errorf("%s:%d: %w", path, n, jerr)
+			}
+			offset += int64(len(line))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if len(r.offsets) == 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s: no recorded frames", path)
+	}
+	return r, nil
+}
+
+// seek shows frame i, clamped to the recording.
+func (r *replay) seek(i int) error {
+	i = max(0, min(i, len(r.offsets)-1))
+	line, err := bufio.NewReader(io.NewSectionReader(r.f, r.offsets[i], 1<<62)).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	var frame recordFrame
+	if err := json.Unmarshal(line, &frame); err != nil {
+		return fmt.Errorf("frame %d: %w", i+1, err)
+	}
+	r.pos, r.frame = i, frame
+	return nil
+}
+
+func (r *replay) step(n int) error {
+	return r.seek(r.pos + n)
+}
+
+// seekBy moves to the first frame at least d after the current one, or
+// before it for a negative d.
+func (r *replay) seekBy(d time.Duration) error {
+	target := r.times[max(r.pos, 0)].Add(d)
+	return r.seek(sort.Search(len(r.times), func(i int) bool {
+		return !r.times[i].Before(target)
+	}))
+}
+
+func (r *replay) atEnd() bool {
+	return r.pos == len(r.offsets)-1
+}
+
+// processes returns a copy of the current frame's processes, which callers
+// sort and filter in place.
+func (r *replay) processes() []ProcessIO {
+	return append([]ProcessIO(nil), r.frame.Processes...)
+}
+
+func (r *replay) status() string {
+	s := fmt.Sprintf("REPLAY %s, frame %d of %d", r.frame.Time.Local().Format(time.DateTime), r.pos+1, len(r.offsets))
+	if r.atEnd() {
+		s += " (end)"
+	}
+	return s + " | , . step  [ ] 1m  { } 10m"
+}
+
+// stats returns the header gauges and totals as recorded in the current
+// frame.
+func (r *replay) stats() (*widgets.Gauge, *widgets.Gauge, ioTotals, error) {
+	cpuGauge := widgets.NewGauge()
+	cpuGauge.Title = "CPU Usage"
+	cpuGauge.Percent = int(r.frame.CPUPercent)
+	memGauge := widgets.NewGauge()
+	memGauge.Title = "Memory Usage"
+	memGauge.Percent = int(r.frame.MemPercent)
+	return cpuGauge, memGauge, r.frame.Totals, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+// ring is a fixed-size buffer keeping the most recent samples.
+type ring struct {
+	buf  []float64
+	next int
+	full bool
+}
+
+func newRing(size int) *ring {
+	return &ring{buf: make([]float64, max(size, 1))}
+}
+
+func (r *ring) push(v float64) {
+	r.buf[r.next] = v
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) len() int {
+	if r.full {
+		return len(r.buf)
+	}
+	return r.next
+}
+
+// values returns the samples oldest first.
+func (r *ring) values() []float64 {
+	if !r.full {
+		return append([]float64(nil), r.buf[:r.next]...)
+	}
+	return append(append([]float64(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+}
+
+func (r *ring) mean() float64 {
+	n := r.len()
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range r.buf[:n] {
+		sum += v
+	}
+	return sum / float64(n)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// selectableRow reports whether row shows a process or, in a grouped view,
+// a group. Row 0 is always the header.
+func selectableRow(rowPIDs []int32, rowGroups []string, row int) bool {
+	return row > 0 && row < len(rowPIDs) && (rowPIDs[row] != 0 || rowGroups[row] != "")
+}
+
+// nearestRow returns the selectable row closest to row, or 0 when there is
+// none.
+func nearestRow(rowPIDs []int32, rowGroups []string, row int) int {
+	for d := 0; d < len(rowPIDs); d++ {
+		if selectableRow(rowPIDs, rowGroups, row-d) {
+			return row - d
+		}
+		if selectableRow(rowPIDs, rowGroups, row+d) {
+			return row + d
+		}
+	}
+	return 0
+}
+
+// stepRow moves from row to the next selectable row in direction dir,
+// staying put at either end of the table.
+func stepRow(rowPIDs []int32, rowGroups []string, row, dir int) int {
+	for i := row + dir; i > 0 && i < len(rowPIDs); i += dir {
+		if selectableRow(rowPIDs, rowGroups, i) {
+			return i
+		}
+	}
+	return row
+}
+
+// signalProcess sends SIGTERM, or SIGKILL when kill is set, and describes
+// the outcome for the status line.
+func signalProcess(pid int32, name string, kill bool) string {
+	signal := "SIGTERM"
+	if kill {
+		signal = "SIGKILL"
+	}
+	p, err := process.NewProcess(pid)
+	if err == nil {
+		if kill {
+			err = p.Kill()
+		} else {
+			err = p.Terminate()
+		}
+	}
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return fmt.Sprintf("%s to PID %d (%s) denied: the process belongs to another user", signal, pid, name)
+	case err != nil:
+		return fmt.Sprintf("%s to PID %d (%s) failed: %v", signal, pid, name, err)
+	}
+	return fmt.Sprintf("Sent %s to PID %d (%s)", signal, pid, name)
+}
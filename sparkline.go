@@ -0,0 +1,29 @@
+package main
+
+import "strings"
+
+// historyLength is the number of samples shown in the per-process trend column
+const historyLength = 8
+
+var showSparklines bool
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as block characters scaled to their own maximum,
+// so bursts stand out even for processes doing little I/O overall.
+func sparkline(values []float64) string {
+	var peak float64
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if peak > 0 {
+			level = int(v / peak * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
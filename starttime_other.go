@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// startTicks is only implemented on Linux; CreateTime alone identifies
+// processes elsewhere.
+func startTicks(pid int32) uint64 {
+	return 0
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Strict mode records why processes were left out of the table instead of
+// skipping them silently.
+var strictMode bool
+
+// strictMaxSkip, with -strict, is the largest fraction of processes a
+// sample may skip before go-iotop gives up with a non-zero exit (0 disables)
+var strictMaxSkip float64
+
+var (
+	skipReasons  = make(map[string]int)
+	sampledCount int
+)
+
+func recordSkip(stage string, err error) {
+	if !strictMode {
+		return
+	}
+
+	reason := err.Error()
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		reason = "permission denied"
+	case errors.Is(err, fs.ErrNotExist):
+		reason = "process exited"
+	case errors.As(err, &pathErr):
+		// Drop the per-PID path so identical failures are counted together
+		reason = pathErr.Err.Error()
+	}
+	skipReasons[stage+": "+reason]++
+}
+
+func resetSkips(total int) {
+	clear(skipReasons)
+	sampledCount = total
+}
+
+// checkSkipLimit returns an error once the last sample skipped more than
+// strictMaxSkip of the processes. Every mode checks it after sampling.
+func checkSkipLimit() error {
+	if !strictMode || strictMaxSkip <= 0 || float64(skippedCount()) <= strictMaxSkip*float64(sampledCount) {
+		return nil
+	}
+	return fmt.Errorf("%d of %d processes skipped, more than -strict-max-skip %v allows", skippedCount(), sampledCount, strictMaxSkip)
+}
+
+func skippedCount() int {
+	n := 0
+	for _, count := range skipReasons {
+		n += count
+	}
+	return n
+}
+
+// skipSummary lists the skip reasons of the last sample, most common first.
+func skipSummary() string {
+	if len(skipReasons) == 0 {
+		return "none"
+	}
+
+	reasons := make([]string, 0, len(skipReasons))
+	for reason := range skipReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if skipReasons[reasons[i]] != skipReasons[reasons[j]] {
+			return skipReasons[reasons[i]] > skipReasons[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		parts = append(parts, fmt.Sprintf("%s (%d)", reason, skipReasons[reason]))
+	}
+	return fmt.Sprintf("%d of %d processes skipped: %s", skippedCount(), sampledCount, strings.Join(parts, ", "))
+}
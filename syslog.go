@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// sysLogger, when -syslog is set, receives a summary of the syslogTop
+// first processes every syslogInterval
+var (
+	sysLogger      *log.Logger
+	syslogInterval time.Duration
+	syslogTop      int
+	lastSyslog     time.Time
+)
+
+// logSummary runs after every sample, so the summary is written whichever
+// mode is sampling: the UI, -batch, -summary, -listen or the plain fallback.
+func logSummary(processes []ProcessIO, now time.Time) {
+	if sysLogger == nil {
+		return
+	}
+	if lastSyslog.IsZero() {
+		// The first summary comes one interval in, once rates are real
+		lastSyslog = now
+		return
+	}
+	if now.Sub(lastSyslog) < syslogInterval {
+		return
+	}
+	lastSyslog = now
+	for _, p := range processes[:min(syslogTop, len(processes))] {
+		sysLogger.Printf("pid=%d name=%q read=%s/s write=%s/s",
+			p.PID, p.Name, humanizeBytes(p.ReadRate), humanizeBytes(p.WriteRate))
+	}
+}
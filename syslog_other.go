@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+// openSyslog is not available on platforms without log/syslog.
+func openSyslog(severity string) (*log.Logger, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
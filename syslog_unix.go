@@ -0,0 +1,26 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/syslog"
+)
+
+var syslogSeverities = map[string]syslog.Priority{
+	"debug":   syslog.LOG_DEBUG,
+	"info":    syslog.LOG_INFO,
+	"notice":  syslog.LOG_NOTICE,
+	"warning": syslog.LOG_WARNING,
+	"err":     syslog.LOG_ERR,
+}
+
+// openSyslog returns a logger writing to the system log at the given severity.
+func openSyslog(severity string) (*log.Logger, error) {
+	priority, ok := syslogSeverities[severity]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog severity %q", severity)
+	}
+	return syslog.NewLogger(syslog.LOG_DAEMON|priority, 0)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var topFilesHeaders = []string{"Path", "Processes", "Size", "Growth/s", "Held By"}
+
+var topFilesWidths = []int{40, 10, 10, 12, 30}
+
+type hotFile struct {
+	Path  string
+	Names []string
+	Size  int64
+	Rate  float64
+	sized bool
+}
+
+// topFilesRows ranks open files across all processes by how many processes
+// hold them open. Growth comes from the file size tracking, which also runs
+// while this view is shown.
+func topFilesRows(processes []ProcessIO, limit int) [][]string {
+	files := make(map[string]*hotFile)
+	for _, p := range processes {
+		// A process may hold the same file open on several descriptors
+		seen := make(map[string]bool)
+		for _, path := range p.Paths {
+			if !strings.HasPrefix(path, "/") || seen[path] {
+				continue
+			}
+			seen[path] = true
+			f, ok := files[path]
+			if !ok {
+				f = &hotFile{Path: path}
+				if g, ok := fileSizes[path]; ok {
+					f.Size, f.Rate, f.sized = g.Size, g.Rate, true
+				}
+				files[path] = f
+			}
+			f.Names = append(f.Names, p.Name)
+		}
+	}
+
+	entries := make([]*hotFile, 0, len(files))
+	for _, f := range files {
+		entries = append(entries, f)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if len(a.Names) != len(b.Names) {
+			return len(a.Names) > len(b.Names)
+		}
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		return a.Path < b.Path
+	})
+
+	rows := [][]string{topFilesHeaders}
+	for _, f := range entries[:min(limit, len(entries))] {
+		size, rate := "-", "-"
+		if f.sized {
+			size = humanizeBytes(float64(f.Size))
+			rate = humanizeBytes(f.Rate)
+		}
+		sort.Strings(f.Names)
+		rows = append(rows, []string{
+			f.Path,
+			fmt.Sprintf("%d", len(f.Names)),
+			size,
+			rate,
+			strings.Join(f.Names, ", "),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// treeMode shows processes under their parents, with each row's rates, CPU
+// and memory including those of all its descendants.
+var treeMode bool
+
+// collapsed holds the PIDs whose children are hidden in tree mode.
+var collapsed = make(map[int32]bool)
+
+// treeRow is one visible line of the process tree.
+type treeRow struct {
+	Total       ProcessIO
+	Depth       int
+	HasChildren bool
+}
+
+// indent prefixes a tree row's name with its depth and whether it can be
+// expanded or collapsed.
+func (r treeRow) indent() string {
+	marker := "  "
+	if r.HasChildren {
+		marker = "▾ "
+		if collapsed[r.Total.PID] {
+			marker = "▸ "
+		}
+	}
+	return strings.Repeat("  ", r.Depth) + marker
+}
+
+// processTree lays processes out depth first under their parents. Processes
+// whose parent isn't in the list are roots. Siblings are ordered by the
+// active sort key applied to their subtree totals, and collapsed subtrees
+// are summed into their root but not listed.
+func processTree(processes []ProcessIO) []treeRow {
+	index := make(map[int32]int, len(processes))
+	for i, p := range processes {
+		index[p.PID] = i
+	}
+	children := make([][]int, len(processes))
+	var roots []int
+	for i, p := range processes {
+		if parent, ok := index[p.PPID]; ok && parent != i {
+			children[parent] = append(children[parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	totals := make([]ProcessIO, len(processes))
+	visited := make([]bool, len(processes))
+	var rollUp func(i int)
+	rollUp = func(i int) {
+		visited[i] = true
+		totals[i] = processes[i]
+		for _, c := range children[i] {
+			if !visited[c] {
+				rollUp(c)
+				totals[i].add(totals[c])
+			}
+		}
+	}
+	for _, i := range roots {
+		rollUp(i)
+	}
+	// PID reuse can leave a parent loop with no root; show it from wherever
+	// it is first found rather than dropping it
+	for i := range processes {
+		if !visited[i] {
+			roots = append(roots, i)
+			rollUp(i)
+		}
+	}
+
+	rows := make([]treeRow, 0, len(processes))
+	placed := make([]bool, len(processes))
+	var walk func(list []int, depth int)
+	walk = func(list []int, depth int) {
+		sort.SliceStable(list, func(a, b int) bool {
+			if sortReverse {
+				return lessProcess(totals[list[b]], totals[list[a]], currentSort)
+			}
+			return lessProcess(totals[list[a]], totals[list[b]], currentSort)
+		})
+		for _, i := range list {
+			if placed[i] {
+				continue
+			}
+			placed[i] = true
+			rows = append(rows, treeRow{Total: totals[i], Depth: depth, HasChildren: len(children[i]) > 0})
+			if !collapsed[processes[i].PID] {
+				walk(children[i], depth+1)
+			}
+		}
+	}
+	walk(roots, 0)
+	return rows
+}
+
+// hasChildren reports whether any process in processes has pid as parent.
+func hasChildren(processes []ProcessIO, pid int32) bool {
+	for _, p := range processes {
+		if p.PPID == pid && p.PID != pid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// trendLength is how many samples the read and write graphs keep, for each
+// process and for the system totals
+const trendLength = 120
+
+// showTrendPanel adds a graph of total throughput above the process table
+var showTrendPanel bool
+
+var totalReadHistory = newRing(trendLength)
+
+// waitTrendPID is the process shown in the detail view. Only its I/O wait
+// is kept, in its snapshot's WaitTrend, from when the view opens until it
+// closes.
+var waitTrendPID int32
+
+// traceWait starts keeping the I/O wait of pid, dropping the history of
+// the process traced before. A pid of 0 stops tracing.
+func traceWait(pid int32) {
+	if pid == waitTrendPID {
+		return
+	}
+	if snap := snapshots[waitTrendPID]; snap != nil {
+		snap.WaitTrend = nil
+	}
+	waitTrendPID = pid
+}
+
+// trendPlot graphs read (green) and write (red) rates in rect, keeping the
+// newest samples that fit. It returns nil until there are two samples to
+// connect.
+func trendPlot(title string, read, write *ring, rect image.Rectangle) *widgets.Plot {
+	if read == nil || read.len() < 2 {
+		return nil
+	}
+	plot := widgets.NewPlot()
+	plot.SetRect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+	plot.ShowAxes = false
+	plot.LineColors = []ui.Color{ui.ColorGreen, ui.ColorRed}
+
+	width := max(plot.Inner.Dx(), 2)
+	var peak float64
+	for _, r := range []*ring{read, write} {
+		values := r.values()
+		values = values[max(len(values)-width, 0):]
+		for _, v := range values {
+			peak = max(peak, v)
+		}
+		plot.Data = append(plot.Data, values)
+	}
+	// A flat zero line would otherwise divide by a zero maximum
+	plot.MaxVal = max(peak, 1)
+	plot.Title = fmt.Sprintf("%s (read green, write red, peak %s/s)", title, humanizeBytes(peak))
+	return plot
+}
+
+// waitPlot graphs the I/O wait percentage in rect, or returns nil until
+// there are two samples. Without kernel delay accounting the line stays at
+// zero.
+func waitPlot(wait *ring, rect image.Rectangle) *widgets.Plot {
+	if wait == nil || wait.len() < 2 {
+		return nil
+	}
+	plot := widgets.NewPlot()
+	plot.SetRect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y)
+	plot.ShowAxes = false
+	plot.LineColors = []ui.Color{ui.ColorYellow}
+
+	values := wait.values()
+	values = values[max(len(values)-max(plot.Inner.Dx(), 2), 0):]
+	var peak float64
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	plot.Data = [][]float64{values}
+	plot.MaxVal = max(peak, 1)
+	plot.Title = fmt.Sprintf("I/O wait (peak %s%%)", formatPercent(peak))
+	return plot
+}
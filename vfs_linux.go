@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// vfsCounters reads the syscall-level rchar/wchar counters from
+// /proc/<pid>/io, which gopsutil does not expose.
+func vfsCounters(pid int32) (read, write uint64, err error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/io", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch key {
+		case "rchar":
+			read = n
+		case "wchar":
+			write = n
+		}
+	}
+	return read, write, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// vfsCounters is only implemented on Linux.
+func vfsCounters(pid int32) (read, write uint64, err error) {
+	return 0, 0, errors.New("VFS accounting is only supported on Linux")
+}